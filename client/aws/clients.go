@@ -1,16 +1,22 @@
 package aws
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/aws/aws-sdk-go/service/servicequotas"
 	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -21,6 +27,10 @@ import (
 )
 
 const (
+	// credentialsExpiryWindow is the time before the expiration of assumed role
+	// credentials at which they are refreshed, so that long living clients
+	// never use expired credentials.
+	credentialsExpiryWindow = 5 * time.Minute
 	// trustedAdvisorRegion describes the AWS region in which the trusted advisor
 	// service is available.
 	trustedAdvisorRegion = "us-east-1"
@@ -35,10 +45,14 @@ type Config struct {
 }
 
 type Clients struct {
-	AutoScaling    *autoscaling.AutoScaling
-	CloudFormation *cloudformation.CloudFormation
+	// Region is the AWS region all regional clients operate in.
+	Region string
+
+	AutoScaling    autoscalingiface.AutoScalingAPI
+	CloudFormation cloudformationiface.CloudFormationAPI
 	EC2            ec2iface.EC2API
 	ELB            elbiface.ELBAPI
+	ELBv2          elbv2iface.ELBV2API
 	ServiceQuotas  servicequotasiface.ServiceQuotasAPI
 	STS            stsiface.STSAPI
 	Support        supportiface.SupportAPI
@@ -72,11 +86,14 @@ func NewClients(config Config) (Clients, error) {
 
 	var c Clients
 	if config.RoleARN != "" {
-		creds := stscreds.NewCredentials(s, config.RoleARN)
+		creds := stscreds.NewCredentials(s, config.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.ExpiryWindow = credentialsExpiryWindow
+		})
 		c = newClients(s, &aws.Config{Credentials: creds})
 	} else {
 		c = newClients(s)
 	}
+	c.Region = config.Region
 
 	return c, nil
 }
@@ -89,6 +106,7 @@ func newClients(session *session.Session, configs ...*aws.Config) Clients {
 		CloudFormation: cloudformation.New(session, configs...),
 		EC2:            ec2.New(session, configs...),
 		ELB:            elb.New(session, configs...),
+		ELBv2:          elbv2.New(session, configs...),
 		ServiceQuotas:  servicequotas.New(session, configs...),
 		STS:            sts.New(session, configs...),
 		Support:        support.New(session, supportConfigs...),
@@ -2,11 +2,14 @@ package aws
 
 import (
 	"github.com/giantswarm/aws-collector/flag/service/aws/hostaccesskey"
+	"github.com/giantswarm/aws-collector/flag/service/aws/regiondiscovery"
 	"github.com/giantswarm/aws-collector/flag/service/aws/trustedadvisor"
 )
 
 type AWS struct {
-	HostAccessKey  hostaccesskey.HostAccessKey
-	Region         string
-	TrustedAdvisor trustedadvisor.TrustedAdvisor
+	HostAccessKey   hostaccesskey.HostAccessKey
+	Region          string
+	RegionDiscovery regiondiscovery.RegionDiscovery
+	Regions         string
+	TrustedAdvisor  trustedadvisor.TrustedAdvisor
 }
@@ -0,0 +1,6 @@
+package regiondiscovery
+
+type RegionDiscovery struct {
+	Enabled string
+	TTL     string
+}
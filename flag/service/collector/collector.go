@@ -0,0 +1,15 @@
+package collector
+
+import (
+	"github.com/giantswarm/aws-collector/flag/service/collector/polling"
+	"github.com/giantswarm/aws-collector/flag/service/collector/servicequota"
+	"github.com/giantswarm/aws-collector/flag/service/collector/snapshot"
+	"github.com/giantswarm/aws-collector/flag/service/collector/tagcompliance"
+)
+
+type Collector struct {
+	Polling       polling.Polling
+	ServiceQuota  servicequota.ServiceQuota
+	Snapshot      snapshot.Snapshot
+	TagCompliance tagcompliance.TagCompliance
+}
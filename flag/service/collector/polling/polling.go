@@ -0,0 +1,7 @@
+package polling
+
+type Polling struct {
+	Enabled   string
+	Interval  string
+	Intervals string
+}
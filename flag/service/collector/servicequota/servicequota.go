@@ -0,0 +1,5 @@
+package servicequota
+
+type ServiceQuota struct {
+	Quotas string
+}
@@ -0,0 +1,5 @@
+package snapshot
+
+type Snapshot struct {
+	Retention string
+}
@@ -0,0 +1,5 @@
+package tagcompliance
+
+type TagCompliance struct {
+	RequiredTags string
+}
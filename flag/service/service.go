@@ -4,11 +4,13 @@ import (
 	"github.com/giantswarm/operatorkit/v5/pkg/flag/service/kubernetes"
 
 	"github.com/giantswarm/aws-collector/flag/service/aws"
+	"github.com/giantswarm/aws-collector/flag/service/collector"
 	"github.com/giantswarm/aws-collector/flag/service/installation"
 )
 
 type Service struct {
 	AWS          aws.AWS
+	Collector    collector.Collector
 	Installation installation.Installation
 	Kubernetes   kubernetes.Kubernetes
 }
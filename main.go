@@ -105,8 +105,21 @@ func mainE(ctx context.Context) error {
 	daemonCommand.PersistentFlags().String(f.Service.AWS.HostAccessKey.Secret, "", "Secret of the AWS access key for the host cluster account. If empty, guest cluster account is used.")
 	daemonCommand.PersistentFlags().String(f.Service.AWS.HostAccessKey.Session, "", "Session token of the AWS access key for the host cluster account. If empty, guest cluster token is used.")
 	daemonCommand.PersistentFlags().String(f.Service.AWS.Region, "", "Region for checking for orphaned AWS resources.")
+	daemonCommand.PersistentFlags().String(f.Service.AWS.RegionDiscovery.Enabled, "", "Whether metrics are collected in every enabled region of an account which contains resources of the installation.")
+	daemonCommand.PersistentFlags().String(f.Service.AWS.RegionDiscovery.TTL, "1h", "Time discovered regions are cached for, e.g. 1h.")
+	daemonCommand.PersistentFlags().String(f.Service.AWS.Regions, "", "Comma separated list of additional regions metrics are collected in, e.g. eu-west-1,us-east-1.")
 	daemonCommand.PersistentFlags().String(f.Service.AWS.TrustedAdvisor.Enabled, "", "Whether trusted advisor metrics collection is enabled.")
 
+	daemonCommand.PersistentFlags().String(f.Service.Collector.Polling.Enabled, "", "Whether collectors refresh their metrics in the background instead of on every scrape.")
+	daemonCommand.PersistentFlags().String(f.Service.Collector.Polling.Interval, "1m", "Interval in which collectors refresh their metrics in the background, e.g. 1m.")
+	daemonCommand.PersistentFlags().String(f.Service.Collector.Polling.Intervals, "", "Comma separated list of per collector refresh intervals overriding the default one, e.g. servicequota=12h,elb=5m.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Collector.ServiceQuota.Quotas, "", "Comma separated list of service quotas to collect in the format <service code>/<quota code>=<name>, e.g. vpc/L-FE5A380F=nat-gateway. If empty, a default list is used.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Collector.Snapshot.Retention, "720h", "Age after which EBS snapshots are reported as expired, e.g. 720h.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Collector.TagCompliance.RequiredTags, "", "Comma separated list of tags every AWS resource of the installation must have. If empty, the installation, cluster and organization tags are required.")
+
 	daemonCommand.PersistentFlags().String(f.Service.Installation.Name, "", "Installation name for tagging AWS resources.")
 
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.Address, "http://127.0.0.1:6443", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
@@ -1,21 +1,43 @@
 package collector
 
 import (
-	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/controller/key"
+	"github.com/giantswarm/aws-collector/service/internal/cache"
+)
+
+const (
+	// __ASGCache__ is used as temporal cache key to save the details of ASGs
+	// fetched with one API call per ASG.
+	prefixASGCacheKey = "__ASGCache__"
 )
 
 const (
 	// labelASG is the metric's label key that will hold the ASG name.
 	labelASG = "asg"
+	// labelHealthStatus is the metric's label key that will hold the health
+	// status of ASG instances.
+	labelHealthStatus = "health_status"
+	// labelLifecycleState is the metric's label key that will hold the
+	// lifecycle state of ASG instances.
+	labelLifecycleState = "lifecycle_state"
+	// labelProcess is the metric's label key that will hold the name of a
+	// scaling process.
+	labelProcess = "process"
+)
+
+const (
+	healthStatusHealthy   = "Healthy"
+	healthStatusUnhealthy = "Unhealthy"
 )
 
 const (
@@ -24,6 +46,18 @@ const (
 	subsystemASG = "asg"
 )
 
+// asgLifecycleStates are the lifecycle states ASG instances are always
+// reported in, even if no instance is in them. Instances in other states are
+// reported as well.
+var asgLifecycleStates = []string{
+	autoscaling.LifecycleStatePending,
+	autoscaling.LifecycleStatePendingWait,
+	autoscaling.LifecycleStateInService,
+	autoscaling.LifecycleStateStandby,
+	autoscaling.LifecycleStateTerminating,
+	autoscaling.LifecycleStateTerminatingWait,
+}
+
 var (
 	asgDesiredDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystemASG, "desired_count"),
@@ -34,6 +68,80 @@ var (
 			labelCluster,
 			labelInstallation,
 			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgMinSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "min_size"),
+		"Gauge about the minimum number of EC2 instances in the ASG.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgMaxSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "max_size"),
+		"Gauge about the maximum number of EC2 instances in the ASG.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instances"),
+		"Gauge about the number of EC2 instances in the ASG by lifecycle state.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelLifecycleState,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgInstancesHealthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instances_health"),
+		"Gauge about the number of EC2 instances in the ASG by health status.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelHealthStatus,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgSuspendedProcessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "suspended_process"),
+		"Gauge about the scaling processes suspended in the ASG. 1 = suspended",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelProcess,
+			labelRegion,
 		},
 		nil,
 	)
@@ -47,6 +155,7 @@ var (
 			labelCluster,
 			labelInstallation,
 			labelOrganization,
+			labelRegion,
 		},
 		nil,
 	)
@@ -62,10 +171,14 @@ type ASGConfig struct {
 
 // ASG is the main struct for this collector.
 type ASG struct {
-	helper *helper
-	logger micrologger.Logger
+	activityCache *scalingActivityCache
+	cache         *asgCache
+	helper        *helper
+	logger        micrologger.Logger
 
 	installationName string
+	// now is only meant to be replaced in tests.
+	now func() time.Time
 }
 
 // NewASG creates a new AutoScalingGroup metrics collector.
@@ -82,43 +195,83 @@ func NewASG(config ASGConfig) (*ASG, error) {
 	}
 
 	a := &ASG{
-		helper: config.Helper,
-		logger: config.Logger,
+		// Scaling activities are kept as long as they are looked back at, so
+		// that only newer activities have to be fetched.
+		activityCache: newScalingActivityCache(scalingActivityLookback),
+		cache:         newASGCache(time.Minute * 5),
+		helper:        config.Helper,
+		logger:        config.Logger,
 
 		installationName: config.InstallationName,
+		now:              time.Now,
 	}
 
 	return a, nil
 }
 
-// Collect is the main metrics collection function.
-func (a *ASG) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := a.helper.ListReconciledClusters()
+// asgCache holds the details of ASGs which take one or more API calls per ASG
+// to fetch, so that large installations do not run into API throttling.
+type asgCache struct {
+	cache *cache.StringCache
+}
+
+// asgDetails are the details of an ASG which are not part of the
+// DescribeAutoScalingGroups response.
+type asgDetails struct {
+	// Activities are the recent scaling activities of the ASG, newest first.
+	// They are not cached with the other details, but kept in the scaling
+	// activity cache and updated on every collection.
+	Activities []scalingActivity `json:"-"`
+	// Hooks are the lifecycle hook names of the ASG keyed by transition. They
+	// are only fetched if instances wait for a lifecycle hook, and are nil
+	// until then.
+	Hooks map[string][]string
+	// Refresh is the latest instance refresh of the ASG, if any.
+	Refresh *instanceRefresh
+}
+
+func newASGCache(expiration time.Duration) *asgCache {
+	cache := &asgCache{
+		cache: cache.NewStringCache(expiration),
+	}
+
+	return cache
+}
+
+// Get returns the cached details of the given ASG, or nil if there are none.
+func (n *asgCache) Get(accountID string, region string, asgName string) (*asgDetails, error) {
+	raw, exists := n.cache.Get(getASGCacheKey(accountID, region, asgName))
+	if !exists {
+		return nil, nil
+	}
+
+	var c asgDetails
+	err := json.Unmarshal(raw, &c)
 	if err != nil {
-		return microerror.Mask(err)
+		return nil, microerror.Mask(err)
 	}
 
-	awsClientsList, err := a.helper.GetAWSClients(context.Background(), reconciledClusters)
+	return &c, nil
+}
+
+func (n *asgCache) Set(accountID string, region string, asgName string, content asgDetails) error {
+	contentSerialized, err := json.Marshal(content)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
+	n.cache.Set(getASGCacheKey(accountID, region, asgName), contentSerialized)
 
-		g.Go(func() error {
-			err := a.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+	return nil
+}
 
-			return nil
-		})
-	}
+func getASGCacheKey(accountID string, region string, asgName string) string {
+	return prefixASGCacheKey + accountID + "/" + region + "/" + asgName
+}
 
-	err = g.Wait()
+// Collect is the main metrics collection function.
+func (a *ASG) Collect(ch chan<- prometheus.Metric) error {
+	err := a.helper.CollectForAccounts(ch, subsystemASG, a.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -129,17 +282,26 @@ func (a *ASG) Collect(ch chan<- prometheus.Metric) error {
 // Describe emits the description for the metrics collected here.
 func (a *ASG) Describe(ch chan<- *prometheus.Desc) error {
 	ch <- asgDesiredDesc
+	ch <- asgMinSizeDesc
+	ch <- asgMaxSizeDesc
+	ch <- asgInstancesDesc
+	ch <- asgInstancesHealthDesc
+	ch <- asgAvailabilityZoneInstancesDesc
+	ch <- asgAvailabilityZoneImbalanceDesc
+	ch <- asgSuspendedProcessDesc
+	ch <- asgLifecycleHookWaitDesc
+	ch <- asgFailedActivitiesDesc
+	ch <- asgLastSuccessfulActivityDesc
+	ch <- asgInstanceRefreshStatusDesc
+	ch <- asgInstanceRefreshCompletionDesc
+	ch <- asgInstanceRefreshInstancesToUpdateDesc
+	ch <- asgInstanceRefreshStartDesc
 	ch <- asgInserviceDesc
 	return nil
 }
 
 // collectForAccount collects and emits metrics for one AWS account.
-func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	account, err := a.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
 	var nextToken *string
 	for {
 		var autoScalingGroups []*autoscaling.Group
@@ -156,7 +318,9 @@ func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 		}
 
 		for _, asg := range autoScalingGroups {
-			var cluster, installation, organization string
+			// The node pool ID is empty for ASGs of the control plane, which
+			// are not managed by an AWSMachineDeployment.
+			var cluster, installation, nodePool, organization string
 
 			for _, tag := range asg.Tags {
 				switch *tag.Key {
@@ -164,6 +328,8 @@ func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 					cluster = *tag.Value
 				case key.TagInstallation:
 					installation = *tag.Value
+				case key.TagMachineDeployment:
+					nodePool = *tag.Value
 				case tagOrganization:
 					organization = *tag.Value
 				}
@@ -178,21 +344,256 @@ func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 				prometheus.GaugeValue,
 				float64(*asg.DesiredCapacity),
 				*asg.AutoScalingGroupName,
-				account,
+				accountID,
+				cluster,
+				installation,
+				organization,
+				awsClients.Region,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				asgMinSizeDesc,
+				prometheus.GaugeValue,
+				float64(aws.Int64Value(asg.MinSize)),
+				*asg.AutoScalingGroupName,
+				accountID,
+				cluster,
+				installation,
+				organization,
+				awsClients.Region,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				asgMaxSizeDesc,
+				prometheus.GaugeValue,
+				float64(aws.Int64Value(asg.MaxSize)),
+				*asg.AutoScalingGroupName,
+				accountID,
 				cluster,
 				installation,
 				organization,
+				awsClients.Region,
 			)
 
+			states := map[string]float64{}
+			for _, state := range asgLifecycleStates {
+				states[state] = 0
+			}
+			health := map[string]float64{
+				healthStatusHealthy:   0,
+				healthStatusUnhealthy: 0,
+			}
+			for _, instance := range asg.Instances {
+				states[aws.StringValue(instance.LifecycleState)]++
+				health[aws.StringValue(instance.HealthStatus)]++
+			}
+
+			for state, count := range states {
+				ch <- prometheus.MustNewConstMetric(
+					asgInstancesDesc,
+					prometheus.GaugeValue,
+					count,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					state,
+					awsClients.Region,
+				)
+			}
+
+			for status, count := range health {
+				ch <- prometheus.MustNewConstMetric(
+					asgInstancesHealthDesc,
+					prometheus.GaugeValue,
+					count,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					status,
+					awsClients.Region,
+				)
+			}
+
+			zones, imbalance := availabilityZoneInstances(asg)
+			for zone, count := range zones {
+				ch <- prometheus.MustNewConstMetric(
+					asgAvailabilityZoneInstancesDesc,
+					prometheus.GaugeValue,
+					count,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					nodePool,
+					zone,
+					awsClients.Region,
+				)
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				asgAvailabilityZoneImbalanceDesc,
+				prometheus.GaugeValue,
+				imbalance,
+				*asg.AutoScalingGroupName,
+				accountID,
+				cluster,
+				installation,
+				organization,
+				nodePool,
+				awsClients.Region,
+			)
+
+			for _, process := range asg.SuspendedProcesses {
+				ch <- prometheus.MustNewConstMetric(
+					asgSuspendedProcessDesc,
+					prometheus.GaugeValue,
+					GaugeValue,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					aws.StringValue(process.ProcessName),
+					awsClients.Region,
+				)
+			}
+
+			details, err := a.getDetails(awsClients, accountID, asg)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			for _, w := range lifecycleHookWaits(asg, details.Hooks, details.Activities) {
+				ch <- prometheus.MustNewConstMetric(
+					asgLifecycleHookWaitDesc,
+					prometheus.GaugeValue,
+					a.now().Sub(w.Since).Seconds(),
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					w.InstanceID,
+					w.LifecycleState,
+					w.Hook,
+					awsClients.Region,
+				)
+			}
+
+			activities := newScalingActivityStats(details.Activities, a.now())
+
+			for k, count := range activities.Failed {
+				ch <- prometheus.MustNewConstMetric(
+					asgFailedActivitiesDesc,
+					prometheus.GaugeValue,
+					count,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					k.Status,
+					k.Reason,
+					awsClients.Region,
+				)
+			}
+
+			if !activities.LastSuccess.IsZero() {
+				ch <- prometheus.MustNewConstMetric(
+					asgLastSuccessfulActivityDesc,
+					prometheus.GaugeValue,
+					float64(activities.LastSuccess.Unix()),
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					awsClients.Region,
+				)
+			}
+
+			if refresh := details.Refresh; refresh != nil {
+				statuses := map[string]float64{}
+				for _, status := range instanceRefreshStatuses {
+					statuses[status] = 0
+				}
+				statuses[refresh.Status] = GaugeValue
+
+				for status, value := range statuses {
+					ch <- prometheus.MustNewConstMetric(
+						asgInstanceRefreshStatusDesc,
+						prometheus.GaugeValue,
+						value,
+						*asg.AutoScalingGroupName,
+						accountID,
+						cluster,
+						installation,
+						organization,
+						nodePool,
+						status,
+						awsClients.Region,
+					)
+				}
+
+				ch <- prometheus.MustNewConstMetric(
+					asgInstanceRefreshCompletionDesc,
+					prometheus.GaugeValue,
+					float64(refresh.PercentageComplete)/100,
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					nodePool,
+					awsClients.Region,
+				)
+
+				ch <- prometheus.MustNewConstMetric(
+					asgInstanceRefreshInstancesToUpdateDesc,
+					prometheus.GaugeValue,
+					float64(refresh.InstancesToUpdate),
+					*asg.AutoScalingGroupName,
+					accountID,
+					cluster,
+					installation,
+					organization,
+					nodePool,
+					awsClients.Region,
+				)
+
+				if refresh.StartTime != nil {
+					ch <- prometheus.MustNewConstMetric(
+						asgInstanceRefreshStartDesc,
+						prometheus.GaugeValue,
+						float64(refresh.StartTime.Unix()),
+						*asg.AutoScalingGroupName,
+						accountID,
+						cluster,
+						installation,
+						organization,
+						nodePool,
+						awsClients.Region,
+					)
+				}
+			}
+
+			// Only instances which are actually in service are counted,
+			// e.g. not the ones still pending or already terminating.
 			ch <- prometheus.MustNewConstMetric(
 				asgInserviceDesc,
 				prometheus.GaugeValue,
-				float64(len(asg.Instances)),
+				states[autoscaling.LifecycleStateInService],
 				*asg.AutoScalingGroupName,
-				account,
+				accountID,
 				cluster,
 				installation,
 				organization,
+				awsClients.Region,
 			)
 		}
 
@@ -203,3 +604,110 @@ func (a *ASG) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 
 	return nil
 }
+
+// getDetails returns the details of the given ASG from the cache, or fetches
+// them if they are not cached. Empty details are cached as well. Scaling
+// activities are fetched on every call, but only the ones started since the
+// last call.
+func (a *ASG) getDetails(awsClients clientaws.Clients, accountID string, asg *autoscaling.Group) (*asgDetails, error) {
+	details, err := a.getCachedDetails(awsClients, accountID, asg)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	details.Activities, err = a.getActivities(awsClients, accountID, asg)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return details, nil
+}
+
+// getActivities returns the recent scaling activities of the given ASG,
+// newest first. The first call for an ASG pages through its recent history,
+// later calls only fetch the activities started since.
+func (a *ASG) getActivities(awsClients clientaws.Clients, accountID string, asg *autoscaling.Group) ([]scalingActivity, error) {
+	name := aws.StringValue(asg.AutoScalingGroupName)
+
+	cached, ok, err := a.activityCache.Get(accountID, awsClients.Region, name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var activities []scalingActivity
+	if ok {
+		activities, err = updateScalingActivities(awsClients, name, cached, a.now())
+	} else {
+		activities, err = getScalingActivities(awsClients, asg, a.now())
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = a.activityCache.Set(accountID, awsClients.Region, name, activities)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return activities, nil
+}
+
+// getCachedDetails returns the details of the given ASG except its scaling
+// activities from the cache, or fetches them if they are not cached. Cached
+// details without lifecycle hooks get them fetched as soon as instances of
+// the ASG wait for a lifecycle hook, and instance refreshes which did not end
+// yet are fetched on every call, so that their progress is not stale.
+func (a *ASG) getCachedDetails(awsClients clientaws.Clients, accountID string, asg *autoscaling.Group) (*asgDetails, error) {
+	name := aws.StringValue(asg.AutoScalingGroupName)
+
+	details, err := a.cache.Get(accountID, awsClients.Region, name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if details != nil {
+		fetchHooks := details.Hooks == nil && hasWaitingInstances(asg)
+		fetchRefresh := instanceRefreshActive(details.Refresh)
+		if !fetchHooks && !fetchRefresh {
+			return details, nil
+		}
+
+		if fetchHooks {
+			details.Hooks, err = getLifecycleHooks(awsClients, asg)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+		if fetchRefresh {
+			details.Refresh, err = getLatestInstanceRefresh(awsClients, name)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		err = a.cache.Set(accountID, awsClients.Region, name, *details)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return details, nil
+	}
+
+	details = &asgDetails{}
+
+	details.Hooks, err = getLifecycleHooks(awsClients, asg)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	details.Refresh, err = getLatestInstanceRefresh(awsClients, name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = a.cache.Set(accountID, awsClients.Region, name, *details)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return details, nil
+}
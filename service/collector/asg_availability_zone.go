@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	asgAvailabilityZoneInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "availability_zone_instances"),
+		"Gauge about the number of EC2 instances of the ASG in service per availability zone.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelAvailabilityZone,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgAvailabilityZoneImbalanceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "availability_zone_imbalance"),
+		"Gauge about the difference between the highest and the lowest number of EC2 instances of the ASG in service across its availability zones.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+// availabilityZoneInstances returns the number of instances in service per
+// availability zone of the given ASG, together with the difference between the
+// highest and the lowest number. Availability zones the ASG is configured for
+// are always returned, so that an empty zone counts towards the imbalance.
+// Instances which are pending or terminating are not counted, as they do not
+// contribute to the availability of the node pool.
+func availabilityZoneInstances(asg *autoscaling.Group) (map[string]float64, float64) {
+	zones := map[string]float64{}
+	for _, zone := range asg.AvailabilityZones {
+		zones[aws.StringValue(zone)] = 0
+	}
+	for _, instance := range asg.Instances {
+		if aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateInService {
+			continue
+		}
+		zones[aws.StringValue(instance.AvailabilityZone)]++
+	}
+
+	if len(zones) == 0 {
+		return zones, 0
+	}
+
+	first := true
+	var min, max float64
+	for _, count := range zones {
+		if first || count < min {
+			min = count
+		}
+		if first || count > max {
+			max = count
+		}
+		first = false
+	}
+
+	return zones, max - min
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+const (
+	// labelInstanceRefreshStatus is the metric's label key that will hold the
+	// status of an instance refresh.
+	labelInstanceRefreshStatus = "status"
+)
+
+// instanceRefreshStatuses are the statuses the latest instance refresh of an
+// ASG is always reported in, so that a status change does not leave stale
+// series behind.
+var instanceRefreshStatuses = []string{
+	autoscaling.InstanceRefreshStatusPending,
+	autoscaling.InstanceRefreshStatusInProgress,
+	autoscaling.InstanceRefreshStatusSuccessful,
+	autoscaling.InstanceRefreshStatusFailed,
+	autoscaling.InstanceRefreshStatusCancelling,
+	autoscaling.InstanceRefreshStatusCancelled,
+}
+
+var (
+	asgInstanceRefreshStatusDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instance_refresh_status"),
+		"Gauge about the status of the latest instance refresh of the ASG. 1 = current status",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelInstanceRefreshStatus,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgInstanceRefreshCompletionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instance_refresh_completion_ratio"),
+		"Gauge about the ratio of EC2 instances already replaced by the latest instance refresh of the ASG, between 0 and 1 like aws_operator_update_max_batch_percentage.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgInstanceRefreshInstancesToUpdateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instance_refresh_instances_to_update"),
+		"Gauge about the number of EC2 instances the latest instance refresh of the ASG still has to replace.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgInstanceRefreshStartDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "instance_refresh_start_timestamp_seconds"),
+		"Unix timestamp of the start of the latest instance refresh of the ASG.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+// instanceRefresh is the part of an instance refresh the ASG collector needs.
+// It is cached between scrapes.
+type instanceRefresh struct {
+	InstancesToUpdate  int64
+	PercentageComplete int64
+	// StartTime is nil for refreshes which did not start yet.
+	StartTime *time.Time
+	Status    string
+}
+
+// instanceRefreshActive returns whether the given instance refresh did not
+// end yet, so that its status and progress are still changing.
+func instanceRefreshActive(refresh *instanceRefresh) bool {
+	if refresh == nil {
+		return false
+	}
+
+	switch refresh.Status {
+	case autoscaling.InstanceRefreshStatusPending, autoscaling.InstanceRefreshStatusInProgress, autoscaling.InstanceRefreshStatusCancelling:
+		return true
+	}
+
+	return false
+}
+
+// getLatestInstanceRefresh returns the latest instance refresh of the given
+// ASG, or nil if it was never refreshed. Instance refreshes are listed newest
+// first, so the first page with a single record is all we need.
+func getLatestInstanceRefresh(awsClients clientaws.Clients, asgName string) (*instanceRefresh, error) {
+	i := &autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: aws.String(asgName),
+		MaxRecords:           aws.Int64(1),
+	}
+	o, err := awsClients.AutoScaling.DescribeInstanceRefreshes(i)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(o.InstanceRefreshes) == 0 {
+		return nil, nil
+	}
+
+	r := o.InstanceRefreshes[0]
+	refresh := &instanceRefresh{
+		InstancesToUpdate:  aws.Int64Value(r.InstancesToUpdate),
+		PercentageComplete: aws.Int64Value(r.PercentageComplete),
+		StartTime:          r.StartTime,
+		Status:             aws.StringValue(r.Status),
+	}
+
+	return refresh, nil
+}
@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+const (
+	// labelLifecycleHook is the metric's label key that will hold the name of
+	// the lifecycle hook an ASG instance waits for.
+	labelLifecycleHook = "lifecycle_hook"
+)
+
+var (
+	asgLifecycleHookWaitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "lifecycle_hook_wait_seconds"),
+		"Gauge about the time an EC2 instance of the ASG has been waiting for the completion of a lifecycle hook.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelInstance,
+			labelLifecycleState,
+			labelLifecycleHook,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+// lifecycleHookTransitions maps the wait states of ASG instances to the
+// transition of the lifecycle hooks they wait for.
+var lifecycleHookTransitions = map[string]string{
+	autoscaling.LifecycleStatePendingWait:     "autoscaling:EC2_INSTANCE_LAUNCHING",
+	autoscaling.LifecycleStateTerminatingWait: "autoscaling:EC2_INSTANCE_TERMINATING",
+}
+
+// lifecycleHookWait is an ASG instance waiting for the completion of a
+// lifecycle hook, e.g. for the draining of a node by the aws-operator, which
+// completes the ControlPlane and NodePool hooks.
+type lifecycleHookWait struct {
+	Hook           string
+	InstanceID     string
+	LifecycleState string
+	// Since is the start of the scaling activity which brought the instance
+	// into its wait state.
+	Since time.Time
+}
+
+// getLifecycleHooks returns the names of the lifecycle hooks of the given ASG
+// keyed by their transition. Most ASGs have no instances waiting for a
+// lifecycle hook, in which case the hooks are not needed and not fetched.
+func getLifecycleHooks(awsClients clientaws.Clients, asg *autoscaling.Group) (map[string][]string, error) {
+	if !hasWaitingInstances(asg) {
+		return nil, nil
+	}
+
+	i := &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: asg.AutoScalingGroupName,
+	}
+	o, err := awsClients.AutoScaling.DescribeLifecycleHooks(i)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	hooks := map[string][]string{}
+	for _, h := range o.LifecycleHooks {
+		transition := aws.StringValue(h.LifecycleTransition)
+		hooks[transition] = append(hooks[transition], aws.StringValue(h.LifecycleHookName))
+	}
+
+	return hooks, nil
+}
+
+// hasWaitingInstances returns whether instances of the given ASG wait for a
+// lifecycle hook.
+func hasWaitingInstances(asg *autoscaling.Group) bool {
+	for _, instance := range asg.Instances {
+		if _, ok := lifecycleHookTransitions[aws.StringValue(instance.LifecycleState)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lifecycleHookWaits returns the lifecycle hooks the instances of the given
+// ASG wait for. The AWS API does not expose since when an instance waits, so
+// it is derived from the latest of the given scaling activities mentioning
+// the instance. Instances without scaling activity are not returned.
+func lifecycleHookWaits(asg *autoscaling.Group, hooks map[string][]string, activities []scalingActivity) []lifecycleHookWait {
+	var waits []lifecycleHookWait
+	for _, instance := range asg.Instances {
+		id := aws.StringValue(instance.InstanceId)
+		state := aws.StringValue(instance.LifecycleState)
+
+		transition, ok := lifecycleHookTransitions[state]
+		if !ok {
+			continue
+		}
+
+		// Scaling activities are ordered newest first, so the first activity
+		// mentioning an instance is the one which brought it into its wait
+		// state.
+		var since time.Time
+		for _, activity := range activities {
+			if strings.Contains(activity.Description, id) {
+				since = activity.StartTime
+				break
+			}
+		}
+		if since.IsZero() {
+			continue
+		}
+
+		for _, hook := range hooks[transition] {
+			waits = append(waits, lifecycleHookWait{
+				Hook:           hook,
+				InstanceID:     id,
+				LifecycleState: state,
+				Since:          since,
+			})
+		}
+	}
+
+	return waits
+}
@@ -0,0 +1,356 @@
+package collector
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/internal/cache"
+)
+
+const (
+	prefixScalingActivityCacheKey = "__ScalingActivityCache__"
+)
+
+const (
+	// labelActivityStatus is the metric's label key that will hold the status
+	// of scaling activities.
+	labelActivityStatus = "status"
+)
+
+const (
+	// scalingActivityWindow is the time window failed scaling activities are
+	// counted in.
+	scalingActivityWindow = time.Hour
+	// scalingActivityLookback is the maximum age of the scaling activities
+	// fetched. It matches the maximum time an instance can wait for a
+	// lifecycle hook.
+	scalingActivityLookback = 48 * time.Hour
+)
+
+var (
+	asgFailedActivitiesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "failed_activities"),
+		"Gauge about the number of failed and cancelled scaling activities of the ASG in the last hour by reason.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelActivityStatus,
+			labelReason,
+			labelRegion,
+		},
+		nil,
+	)
+
+	asgLastSuccessfulActivityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemASG, "last_successful_activity_timestamp_seconds"),
+		"Unix timestamp of the end of the last successful scaling activity of the ASG.",
+		[]string{
+			labelASG,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+// errorCodeRegexp matches the error code AWS puts into the status messages of
+// failed scaling activities, e.g. "Error Code: InsufficientInstanceCapacity".
+var errorCodeRegexp = regexp.MustCompile(`Error Code: ([A-Za-z.]+)`)
+
+// failureReasons are the reasons failed scaling activities are reported with.
+// They are matched as prefixes of the error code, or contained in the status
+// message if it has no error code. Keeping them to a known list bounds the
+// cardinality of the reason label.
+var failureReasons = []string{
+	"InsufficientFreeAddressesInSubnet",
+	"InsufficientInstanceCapacity",
+	"InstanceLimitExceeded",
+	"InvalidLaunchTemplate",
+	"InvalidParameterValue",
+	"MaxSpotInstanceCountExceeded",
+	"SpotMaxPriceTooLow",
+	"UnauthorizedOperation",
+	"Unsupported",
+	"VcpuLimitExceeded",
+}
+
+// scalingActivity is the part of an ASG scaling activity the ASG collector
+// needs. It is cached between scrapes.
+type scalingActivity struct {
+	ActivityID    string
+	Description   string
+	EndTime       time.Time
+	StartTime     time.Time
+	StatusCode    string
+	StatusMessage string
+}
+
+// scalingActivityStats summarizes the recent scaling activities of an ASG.
+type scalingActivityStats struct {
+	// Failed holds the number of failed and cancelled activities in the
+	// scalingActivityWindow, keyed by status and normalized reason.
+	Failed map[failedActivityKey]float64
+	// LastSuccess is the end time of the last successful activity. It is zero
+	// if there is none within scalingActivityLookback.
+	LastSuccess time.Time
+}
+
+type failedActivityKey struct {
+	Reason string
+	Status string
+}
+
+// scalingActivityCache holds the scaling activities of ASGs fetched so far,
+// so that only activities newer than the ones already seen are fetched.
+type scalingActivityCache struct {
+	cache *cache.StringCache
+}
+
+func newScalingActivityCache(expiration time.Duration) *scalingActivityCache {
+	c := &scalingActivityCache{
+		cache: cache.NewStringCache(expiration),
+	}
+
+	return c
+}
+
+// Get returns the cached scaling activities of the given ASG, newest first.
+// The returned bool is false if the activities were never fetched.
+func (c *scalingActivityCache) Get(accountID string, region string, asgName string) ([]scalingActivity, bool, error) {
+	raw, exists := c.cache.Get(getScalingActivityCacheKey(accountID, region, asgName))
+	if !exists {
+		return nil, false, nil
+	}
+
+	var activities []scalingActivity
+	err := json.Unmarshal(raw, &activities)
+	if err != nil {
+		return nil, false, microerror.Mask(err)
+	}
+
+	return activities, true, nil
+}
+
+func (c *scalingActivityCache) Set(accountID string, region string, asgName string, activities []scalingActivity) error {
+	raw, err := json.Marshal(activities)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c.cache.Set(getScalingActivityCacheKey(accountID, region, asgName), raw)
+
+	return nil
+}
+
+func getScalingActivityCacheKey(accountID string, region string, asgName string) string {
+	return prefixScalingActivityCacheKey + accountID + "/" + region + "/" + asgName
+}
+
+// getScalingActivities pages through the scaling activities of the given ASG,
+// which are listed newest first, until the scalingActivityWindow is exceeded,
+// the last successful activity is found and the activities which brought the
+// waiting instances into their wait state are found. Activities older than
+// scalingActivityLookback are never fetched, so that ASGs without successful
+// activity do not page through their whole history.
+func getScalingActivities(awsClients clientaws.Clients, asg *autoscaling.Group, now time.Time) ([]scalingActivity, error) {
+	var activities []scalingActivity
+
+	windowStart := now.Add(-scalingActivityWindow)
+	lookbackStart := now.Add(-scalingActivityLookback)
+
+	waiting := map[string]bool{}
+	for _, instance := range asg.Instances {
+		if _, ok := lifecycleHookTransitions[aws.StringValue(instance.LifecycleState)]; ok {
+			waiting[aws.StringValue(instance.InstanceId)] = true
+		}
+	}
+
+	var successFound bool
+	i := &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: asg.AutoScalingGroupName,
+	}
+	for {
+		o, err := awsClients.AutoScaling.DescribeScalingActivities(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var windowExceeded bool
+		for _, a := range o.Activities {
+			activity := newScalingActivity(a)
+			if activity.StartTime.Before(lookbackStart) {
+				return activities, nil
+			}
+			if activity.StartTime.Before(windowStart) {
+				windowExceeded = true
+			}
+			if activity.StatusCode == autoscaling.ScalingActivityStatusCodeSuccessful {
+				successFound = true
+			}
+			for id := range waiting {
+				if strings.Contains(activity.Description, id) {
+					delete(waiting, id)
+				}
+			}
+
+			activities = append(activities, activity)
+		}
+
+		if windowExceeded && successFound && len(waiting) == 0 {
+			break
+		}
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return activities, nil
+}
+
+// updateScalingActivities pages through the scaling activities of the given
+// ASG which started since the newest of the given cached activities, or since
+// the oldest of them which was not completed yet, and merges them with the
+// cached activities. Cached activities older than scalingActivityLookback are
+// dropped.
+func updateScalingActivities(awsClients clientaws.Clients, asgName string, cached []scalingActivity, now time.Time) ([]scalingActivity, error) {
+	lookbackStart := now.Add(-scalingActivityLookback)
+
+	since := lookbackStart
+	if len(cached) > 0 {
+		since = cached[0].StartTime
+	}
+	for _, activity := range cached {
+		if !scalingActivityCompleted(activity) && activity.StartTime.Before(since) {
+			since = activity.StartTime
+		}
+	}
+
+	var activities []scalingActivity
+
+	i := &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asgName),
+	}
+	var sinceExceeded bool
+	for !sinceExceeded {
+		o, err := awsClients.AutoScaling.DescribeScalingActivities(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, a := range o.Activities {
+			activity := newScalingActivity(a)
+			if activity.StartTime.Before(since) {
+				sinceExceeded = true
+				break
+			}
+
+			activities = append(activities, activity)
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	// The cached activities which started before since were not fetched
+	// again and are all older than the fetched ones.
+	for _, activity := range cached {
+		if activity.StartTime.Before(lookbackStart) {
+			break
+		}
+		if activity.StartTime.Before(since) {
+			activities = append(activities, activity)
+		}
+	}
+
+	return activities, nil
+}
+
+func newScalingActivity(a *autoscaling.Activity) scalingActivity {
+	return scalingActivity{
+		ActivityID:    aws.StringValue(a.ActivityId),
+		Description:   aws.StringValue(a.Description),
+		EndTime:       aws.TimeValue(a.EndTime),
+		StartTime:     aws.TimeValue(a.StartTime),
+		StatusCode:    aws.StringValue(a.StatusCode),
+		StatusMessage: aws.StringValue(a.StatusMessage),
+	}
+}
+
+// scalingActivityCompleted returns whether the status of the given scaling
+// activity is final.
+func scalingActivityCompleted(activity scalingActivity) bool {
+	switch activity.StatusCode {
+	case autoscaling.ScalingActivityStatusCodeSuccessful, autoscaling.ScalingActivityStatusCodeFailed, autoscaling.ScalingActivityStatusCodeCancelled:
+		return true
+	}
+
+	return false
+}
+
+// newScalingActivityStats summarizes the given scaling activities, which are
+// ordered newest first.
+func newScalingActivityStats(activities []scalingActivity, now time.Time) scalingActivityStats {
+	stats := scalingActivityStats{
+		Failed: map[failedActivityKey]float64{},
+	}
+
+	windowStart := now.Add(-scalingActivityWindow)
+
+	for _, activity := range activities {
+		switch activity.StatusCode {
+		case autoscaling.ScalingActivityStatusCodeSuccessful:
+			if stats.LastSuccess.IsZero() {
+				stats.LastSuccess = activity.EndTime
+			}
+		case autoscaling.ScalingActivityStatusCodeFailed, autoscaling.ScalingActivityStatusCodeCancelled:
+			if !activity.StartTime.Before(windowStart) {
+				k := failedActivityKey{
+					Reason: failureReason(activity.StatusMessage),
+					Status: activity.StatusCode,
+				}
+				stats.Failed[k]++
+			}
+		}
+	}
+
+	return stats
+}
+
+// failureReason normalizes the status message of a failed scaling activity to
+// one of the failureReasons, or reasonOther if it matches none of them.
+func failureReason(message string) string {
+	if m := errorCodeRegexp.FindStringSubmatch(message); m != nil {
+		for _, reason := range failureReasons {
+			if strings.HasPrefix(m[1], reason) {
+				return reason
+			}
+		}
+
+		return reasonOther
+	}
+
+	for _, reason := range failureReasons {
+		if strings.Contains(message, reason) {
+			return reason
+		}
+	}
+
+	return reasonOther
+}
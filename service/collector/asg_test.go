@@ -0,0 +1,508 @@
+package collector
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type autoScalingMock struct {
+	autoscalingiface.AutoScalingAPI
+
+	groups []*autoscaling.Group
+	// activities, hooks and refreshes are keyed by ASG name.
+	activities map[string][]*autoscaling.Activity
+	hooks      map[string][]*autoscaling.LifecycleHook
+	refreshes  map[string][]*autoscaling.InstanceRefresh
+
+	// calls counts the API calls per ASG, except DescribeAutoScalingGroups.
+	calls map[string]int
+}
+
+func (a *autoScalingMock) called(asgName *string) {
+	if a.calls == nil {
+		a.calls = map[string]int{}
+	}
+	a.calls[*asgName]++
+}
+
+func (a *autoScalingMock) DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: a.groups}, nil
+}
+
+func (a *autoScalingMock) DescribeInstanceRefreshes(i *autoscaling.DescribeInstanceRefreshesInput) (*autoscaling.DescribeInstanceRefreshesOutput, error) {
+	a.called(i.AutoScalingGroupName)
+	refreshes := a.refreshes[*i.AutoScalingGroupName]
+	if i.MaxRecords != nil && int64(len(refreshes)) > *i.MaxRecords {
+		refreshes = refreshes[:*i.MaxRecords]
+	}
+
+	return &autoscaling.DescribeInstanceRefreshesOutput{InstanceRefreshes: refreshes}, nil
+}
+
+func (a *autoScalingMock) DescribeLifecycleHooks(i *autoscaling.DescribeLifecycleHooksInput) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	a.called(i.AutoScalingGroupName)
+	return &autoscaling.DescribeLifecycleHooksOutput{LifecycleHooks: a.hooks[*i.AutoScalingGroupName]}, nil
+}
+
+// DescribeScalingActivities returns one activity per page in order to cover
+// the pagination.
+func (a *autoScalingMock) DescribeScalingActivities(i *autoscaling.DescribeScalingActivitiesInput) (*autoscaling.DescribeScalingActivitiesOutput, error) {
+	a.called(i.AutoScalingGroupName)
+	activities := a.activities[*i.AutoScalingGroupName]
+
+	var n int
+	if i.NextToken != nil {
+		n, _ = strconv.Atoi(*i.NextToken)
+	}
+	if n >= len(activities) {
+		return &autoscaling.DescribeScalingActivitiesOutput{}, nil
+	}
+
+	o := &autoscaling.DescribeScalingActivitiesOutput{
+		Activities: activities[n : n+1],
+	}
+	if n+1 < len(activities) {
+		o.NextToken = aws.String(strconv.Itoa(n + 1))
+	}
+
+	return o, nil
+}
+
+func asgTags(installation string, cluster string) []*autoscaling.TagDescription {
+	return []*autoscaling.TagDescription{
+		{Key: aws.String(key.TagInstallation), Value: aws.String(installation)},
+		{Key: aws.String(tagCluster), Value: aws.String(cluster)},
+		{Key: aws.String(tagOrganization), Value: aws.String("giantswarm")},
+	}
+}
+
+func asgInstance(id string, zone string, lifecycleState string, healthStatus string) *autoscaling.Instance {
+	return &autoscaling.Instance{
+		AvailabilityZone: aws.String(zone),
+		HealthStatus:     aws.String(healthStatus),
+		InstanceId:       aws.String(id),
+		LifecycleState:   aws.String(lifecycleState),
+	}
+}
+
+func asgActivity(description string, status string, message string, startTime time.Time) *autoscaling.Activity {
+	return &autoscaling.Activity{
+		Description:   aws.String(description),
+		EndTime:       aws.Time(startTime.Add(time.Minute)),
+		StartTime:     aws.Time(startTime),
+		StatusCode:    aws.String(status),
+		StatusMessage: aws.String(message),
+	}
+}
+
+func asgLifecycleHook(name string, transition string) *autoscaling.LifecycleHook {
+	return &autoscaling.LifecycleHook{
+		LifecycleHookName:   aws.String(name),
+		LifecycleTransition: aws.String(transition),
+	}
+}
+
+func Test_ASG_collectForAccount(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	mock := &autoScalingMock{
+		activities: map[string][]*autoscaling.Activity{
+			"al9qy-tcnp-a1b2c": {
+				asgActivity("Launching a new EC2 instance. Status Reason: We currently do not have sufficient m5.xlarge capacity.", autoscaling.ScalingActivityStatusCodeFailed, "We currently do not have sufficient m5.xlarge capacity in the Availability Zone you requested (eu-central-1a). Launching EC2 instance failed. (Service: AmazonEC2; Status Code: 500; Error Code: InsufficientInstanceCapacity)", now.Add(-5*time.Minute)),
+				asgActivity("Launching a new EC2 instance: i-000003", autoscaling.ScalingActivityStatusCodeMidLifecycleAction, "", now.Add(-10*time.Minute)),
+				asgActivity("Launching a new EC2 instance. Status Reason: We currently do not have sufficient m5.xlarge capacity.", autoscaling.ScalingActivityStatusCodeFailed, "We currently do not have sufficient m5.xlarge capacity in the Availability Zone you requested (eu-central-1b). Launching EC2 instance failed. (Service: AmazonEC2; Status Code: 500; Error Code: InsufficientInstanceCapacity)", now.Add(-15*time.Minute)),
+				asgActivity("Launching a new EC2 instance.", autoscaling.ScalingActivityStatusCodeCancelled, "Launching EC2 instance failed.", now.Add(-30*time.Minute)),
+				asgActivity("Terminating EC2 instance: i-000004", autoscaling.ScalingActivityStatusCodeMidLifecycleAction, "", now.Add(-2*time.Hour)),
+				// Failures outside of the window are not counted.
+				asgActivity("Launching a new EC2 instance.", autoscaling.ScalingActivityStatusCodeFailed, "You have requested more vCPU capacity than your current vCPU limit of 32 allows. (Error Code: VcpuLimitExceeded)", now.Add(-3*time.Hour)),
+				// Only the latest activity of an instance is considered.
+				asgActivity("Launching a new EC2 instance: i-000004", autoscaling.ScalingActivityStatusCodeSuccessful, "", now.Add(-48*time.Hour)),
+			},
+		},
+		refreshes: map[string][]*autoscaling.InstanceRefresh{
+			"al9qy-tcnp-a1b2c": {
+				{
+					InstancesToUpdate:  aws.Int64(3),
+					PercentageComplete: aws.Int64(40),
+					StartTime:          aws.Time(now.Add(-20 * time.Minute)),
+					Status:             aws.String(autoscaling.InstanceRefreshStatusInProgress),
+				},
+				// Only the latest instance refresh is reported.
+				{
+					InstancesToUpdate:  aws.Int64(0),
+					PercentageComplete: aws.Int64(100),
+					StartTime:          aws.Time(now.Add(-72 * time.Hour)),
+					Status:             aws.String(autoscaling.InstanceRefreshStatusSuccessful),
+				},
+			},
+		},
+		hooks: map[string][]*autoscaling.LifecycleHook{
+			"al9qy-tcnp-a1b2c": {
+				asgLifecycleHook(key.LifeCycleHookNodePool, "autoscaling:EC2_INSTANCE_TERMINATING"),
+			},
+		},
+		groups: []*autoscaling.Group{
+			{
+				AutoScalingGroupName: aws.String("al9qy-tcnp-a1b2c"),
+				// Availability zones without instances count towards the
+				// imbalance.
+				AvailabilityZones: aws.StringSlice([]string{"eu-central-1a", "eu-central-1b", "eu-central-1c"}),
+				DesiredCapacity:   aws.Int64(4),
+				Instances: []*autoscaling.Instance{
+					asgInstance("i-000001", "eu-central-1a", autoscaling.LifecycleStateInService, healthStatusHealthy),
+					asgInstance("i-000002", "eu-central-1a", autoscaling.LifecycleStateInService, healthStatusUnhealthy),
+					// Instances waiting for a transition without lifecycle
+					// hook are not reported.
+					asgInstance("i-000003", "eu-central-1b", autoscaling.LifecycleStatePendingWait, healthStatusHealthy),
+					asgInstance("i-000004", "eu-central-1b", autoscaling.LifecycleStateTerminatingWait, healthStatusUnhealthy),
+					asgInstance("i-000005", "eu-central-1a", autoscaling.LifecycleStateDetaching, healthStatusHealthy),
+				},
+				MaxSize: aws.Int64(10),
+				MinSize: aws.Int64(3),
+				SuspendedProcesses: []*autoscaling.SuspendedProcess{
+					{ProcessName: aws.String("AZRebalance")},
+					{ProcessName: aws.String("Terminate")},
+				},
+				Tags: append(
+					asgTags("test", "al9qy"),
+					&autoscaling.TagDescription{Key: aws.String(key.TagMachineDeployment), Value: aws.String("a1b2c")},
+				),
+			},
+			// ASGs of other installations are ignored.
+			{
+				AutoScalingGroupName: aws.String("x7k2e-tcnp-d3e4f"),
+				DesiredCapacity:      aws.Int64(1),
+				MaxSize:              aws.Int64(1),
+				MinSize:              aws.Int64(1),
+				Tags:                 asgTags("other", "x7k2e"),
+			},
+		},
+	}
+
+	a, err := NewASG(ASGConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.now = func() time.Time { return now }
+
+	awsClients := clientaws.Clients{
+		AutoScaling: mock,
+		Region:      "eu-central-1",
+	}
+
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			return a.collectForAccount(ch, awsClients, "000000000000")
+		},
+		describe: a.Describe,
+	}
+
+	compareGolden(t, "asg", gatherText(t, c))
+}
+
+// Test_ASG_collectForAccount_cache ensures that the API calls made per ASG
+// are bounded and cached between collections.
+func Test_ASG_collectForAccount_cache(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	// The ASG never had a successful scaling activity, so its whole history
+	// would be paged through without lookback.
+	var activities []*autoscaling.Activity
+	for h := 0; h < 100; h++ {
+		activities = append(activities, asgActivity("Launching a new EC2 instance.", autoscaling.ScalingActivityStatusCodeFailed, "", now.Add(-time.Duration(h)*time.Hour)))
+	}
+
+	mock := &autoScalingMock{
+		activities: map[string][]*autoscaling.Activity{
+			"al9qy-tcnp-a1b2c": activities,
+		},
+		groups: []*autoscaling.Group{
+			{
+				AutoScalingGroupName: aws.String("al9qy-tcnp-a1b2c"),
+				DesiredCapacity:      aws.Int64(1),
+				MaxSize:              aws.Int64(1),
+				MinSize:              aws.Int64(1),
+				Tags:                 asgTags("test", "al9qy"),
+			},
+		},
+	}
+
+	a, err := NewASG(ASGConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.now = func() time.Time { return now }
+
+	awsClients := clientaws.Clients{
+		AutoScaling: mock,
+		Region:      "eu-central-1",
+	}
+
+	// The first collection makes one page per hourly activity within the
+	// lookback including both ends, one page with the first activity beyond
+	// it and one call for the instance refreshes. No lifecycle hooks are
+	// fetched without waiting instances. The second collection only pages
+	// through the activities since the newest one seen, which takes the page
+	// with the newest activity and the page with the one before it.
+	expected := []int{
+		(int(scalingActivityLookback/time.Hour) + 1) + 1 + 1,
+		(int(scalingActivityLookback/time.Hour) + 1) + 1 + 1 + 2,
+	}
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 1000)
+		err = a.collectForAccount(ch, awsClients, "000000000000")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := mock.calls["al9qy-tcnp-a1b2c"]
+		if calls != expected[i] {
+			t.Fatalf("collection %d: expected %d API calls, got %d", i, expected[i], calls)
+		}
+	}
+}
+
+// Test_updateScalingActivities ensures that new scaling activities and
+// activities which were still in progress are fetched, and merged with the
+// cached activities.
+func Test_updateScalingActivities(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	activity := func(id string, status string, startTime time.Time) *autoscaling.Activity {
+		a := asgActivity("Launching a new EC2 instance: "+id, status, "", startTime)
+		a.ActivityId = aws.String(id)
+		return a
+	}
+
+	mock := &autoScalingMock{
+		activities: map[string][]*autoscaling.Activity{
+			"al9qy-tcnp-a1b2c": {
+				activity("a4", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-5*time.Minute)),
+				activity("a3", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-20*time.Minute)),
+				// a2 was in progress when it was cached.
+				activity("a2", autoscaling.ScalingActivityStatusCodeFailed, now.Add(-30*time.Minute)),
+				activity("a1", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-40*time.Minute)),
+				activity("a0", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-50*time.Hour)),
+			},
+		},
+	}
+
+	cached := []scalingActivity{
+		newScalingActivity(activity("a3", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-20*time.Minute))),
+		newScalingActivity(activity("a2", autoscaling.ScalingActivityStatusCodeInProgress, now.Add(-30*time.Minute))),
+		newScalingActivity(activity("a1", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-40*time.Minute))),
+		// Activities beyond the lookback are dropped.
+		newScalingActivity(activity("a0", autoscaling.ScalingActivityStatusCodeSuccessful, now.Add(-50*time.Hour))),
+	}
+
+	awsClients := clientaws.Clients{
+		AutoScaling: mock,
+		Region:      "eu-central-1",
+	}
+
+	activities, err := updateScalingActivities(awsClients, "al9qy-tcnp-a1b2c", cached, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	for _, a := range activities {
+		statuses = append(statuses, a.ActivityID+"="+a.StatusCode)
+	}
+	expected := []string{
+		"a4=" + autoscaling.ScalingActivityStatusCodeSuccessful,
+		"a3=" + autoscaling.ScalingActivityStatusCodeSuccessful,
+		"a2=" + autoscaling.ScalingActivityStatusCodeFailed,
+		"a1=" + autoscaling.ScalingActivityStatusCodeSuccessful,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+
+	// Pages from the newest activity down to the first one before a2.
+	if mock.calls["al9qy-tcnp-a1b2c"] != 4 {
+		t.Fatalf("expected 4 API calls, got %d", mock.calls["al9qy-tcnp-a1b2c"])
+	}
+}
+
+// Test_ASG_getDetails_hooks ensures that the lifecycle hooks of an ASG are
+// fetched as soon as its instances wait for them, even if its details are
+// cached.
+func Test_ASG_getDetails_hooks(t *testing.T) {
+	asg := &autoscaling.Group{
+		AutoScalingGroupName: aws.String("al9qy-tcnp-a1b2c"),
+		Instances: []*autoscaling.Instance{
+			asgInstance("i-000001", "eu-central-1a", autoscaling.LifecycleStateInService, healthStatusHealthy),
+		},
+	}
+
+	mock := &autoScalingMock{
+		hooks: map[string][]*autoscaling.LifecycleHook{
+			"al9qy-tcnp-a1b2c": {
+				asgLifecycleHook(key.LifeCycleHookNodePool, "autoscaling:EC2_INSTANCE_TERMINATING"),
+			},
+		},
+	}
+
+	a, err := NewASG(ASGConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsClients := clientaws.Clients{
+		AutoScaling: mock,
+		Region:      "eu-central-1",
+	}
+
+	details, err := a.getDetails(awsClients, "000000000000", asg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Hooks != nil {
+		t.Fatalf("expected no hooks without waiting instances, got %v", details.Hooks)
+	}
+
+	asg.Instances[0].LifecycleState = aws.String(autoscaling.LifecycleStateTerminatingWait)
+
+	details, err = a.getDetails(awsClients, "000000000000", asg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"autoscaling:EC2_INSTANCE_TERMINATING": {key.LifeCycleHookNodePool},
+	}
+	if !reflect.DeepEqual(details.Hooks, expected) {
+		t.Fatalf("expected hooks %v, got %v", expected, details.Hooks)
+	}
+}
+
+// Test_ASG_getDetails_refresh ensures that instance refreshes are fetched on
+// every collection until they ended, and cached afterwards.
+func Test_ASG_getDetails_refresh(t *testing.T) {
+	asg := &autoscaling.Group{
+		AutoScalingGroupName: aws.String("al9qy-tcnp-a1b2c"),
+	}
+
+	refresh := &autoscaling.InstanceRefresh{
+		InstancesToUpdate:  aws.Int64(3),
+		PercentageComplete: aws.Int64(40),
+		Status:             aws.String(autoscaling.InstanceRefreshStatusInProgress),
+	}
+	mock := &autoScalingMock{
+		refreshes: map[string][]*autoscaling.InstanceRefresh{
+			"al9qy-tcnp-a1b2c": {refresh},
+		},
+	}
+
+	a, err := NewASG(ASGConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsClients := clientaws.Clients{
+		AutoScaling: mock,
+		Region:      "eu-central-1",
+	}
+
+	testCases := []struct {
+		percentage int64
+		status     string
+		expected   int64
+	}{
+		{percentage: 40, status: autoscaling.InstanceRefreshStatusInProgress, expected: 40},
+		{percentage: 80, status: autoscaling.InstanceRefreshStatusInProgress, expected: 80},
+		{percentage: 100, status: autoscaling.InstanceRefreshStatusSuccessful, expected: 100},
+		// Ended refreshes are served from the cache.
+		{percentage: 0, status: autoscaling.InstanceRefreshStatusPending, expected: 100},
+	}
+
+	for i, tc := range testCases {
+		refresh.PercentageComplete = aws.Int64(tc.percentage)
+		refresh.Status = aws.String(tc.status)
+
+		details, err := a.getDetails(awsClients, "000000000000", asg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.Refresh.PercentageComplete != tc.expected {
+			t.Fatalf("collection %d: expected %d%% complete, got %d%%", i, tc.expected, details.Refresh.PercentageComplete)
+		}
+	}
+}
+
+func Test_failureReason(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "case 0: error code",
+			message:  "You have requested more vCPU capacity than your current vCPU limit of 32 allows. (Service: AmazonEC2; Status Code: 400; Error Code: VcpuLimitExceeded)",
+			expected: "VcpuLimitExceeded",
+		},
+		{
+			name:     "case 1: error code with suffix",
+			message:  "The specified launch template does not exist. (Service: AmazonEC2; Status Code: 400; Error Code: InvalidLaunchTemplateId.NotFound)",
+			expected: "InvalidLaunchTemplate",
+		},
+		{
+			name:     "case 2: unknown error code",
+			message:  "Something went wrong. (Service: AmazonEC2; Status Code: 400; Error Code: SomethingWentWrong)",
+			expected: reasonOther,
+		},
+		{
+			name:     "case 3: reason without error code",
+			message:  "Spot bid price is less than Spot market price. SpotMaxPriceTooLow",
+			expected: "SpotMaxPriceTooLow",
+		},
+		{
+			name:     "case 4: empty message",
+			message:  "",
+			expected: reasonOther,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := failureReason(tc.message)
+			if reason != tc.expected {
+				t.Fatalf("expected %#q, got %#q", tc.expected, reason)
+			}
+		})
+	}
+}
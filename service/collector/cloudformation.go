@@ -1,13 +1,10 @@
 package collector
 
 import (
-	"context"
-
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/controller/key"
@@ -35,6 +32,7 @@ var (
 			labelOrganization,
 			labelStackType,
 			labelState,
+			labelRegion,
 		},
 		nil,
 	)
@@ -81,32 +79,7 @@ func NewCloudFormation(config CloudFormationConfig) (*CloudFormation, error) {
 
 // Collect is the main metrics collection function.
 func (cf *CloudFormation) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := cf.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := cf.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := cf.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := cf.helper.CollectForAccounts(ch, subsystemCloudFormation, cf.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -121,18 +94,25 @@ func (cf *CloudFormation) Describe(ch chan<- *prometheus.Desc) error {
 }
 
 // collectForAccount collects metrics for one AWS account.
-func (cf *CloudFormation) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	o, err := awsClients.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{})
-	if err != nil {
-		return microerror.Mask(err)
-	}
+func (cf *CloudFormation) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	var stacks []*cloudformation.Stack
+	{
+		i := &cloudformation.DescribeStacksInput{}
+		for {
+			o, err := awsClients.CloudFormation.DescribeStacks(i)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			stacks = append(stacks, o.Stacks...)
 
-	accountID, err := cf.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
 	}
 
-	for _, stack := range o.Stacks {
+	for _, stack := range stacks {
 		var cluster, installation, name, organization, stackType string
 
 		for _, tag := range stack.Tags {
@@ -170,6 +150,7 @@ func (cf *CloudFormation) collectForAccount(ch chan<- prometheus.Metric, awsClie
 			organization,
 			stackType,
 			*stack.StackStatus,
+			awsClients.Region,
 		)
 	}
 
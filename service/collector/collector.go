@@ -17,7 +17,9 @@ const (
 	labelAccount      = "account"
 	labelAccountID    = "account_id"
 	labelCluster      = "cluster_id"
+	labelCollector    = "collector"
 	labelName         = "name"
 	labelInstallation = "installation"
 	labelOrganization = "organization"
+	labelRegion       = "region"
 )
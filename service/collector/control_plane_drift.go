@@ -0,0 +1,397 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/apiextensions/v3/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	// subsystemControlPlaneDrift will become the second part of the metric
+	// name, right after namespace.
+	subsystemControlPlaneDrift = "control_plane_drift"
+)
+
+const (
+	driftFieldMasterAvailabilityZones = "master_availability_zones"
+	driftFieldMasterInstanceType      = "master_instance_type"
+	driftFieldPodCIDR                 = "pod_cidr"
+	driftFieldRegion                  = "region"
+	driftFieldVPCCIDR                 = "vpc_cidr"
+)
+
+// clusterCreationGracePeriod is the time after the creation of an AWSCluster
+// CR within which its VPC is not reported missing, since the VPC is only
+// created along with the cluster's control plane CloudFormation stack.
+const clusterCreationGracePeriod = 30 * time.Minute
+
+var (
+	controlPlaneDriftMismatchDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemControlPlaneDrift, "mismatch"),
+		"Gauge about fields of a cluster's VPC and master instances differing from its AWSCluster and AWSControlPlane CRs. 1 = differs",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelField,
+			labelRegion,
+		},
+		nil,
+	)
+
+	controlPlaneDriftMissingVPCDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemControlPlaneDrift, "missing_vpc"),
+		"Clusters of the installation which have an AWSCluster CR but no VPC in their region.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type ControlPlaneDriftConfig struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+}
+
+// ControlPlaneDrift compares the VPCs and master instances of the
+// installation's clusters with their AWSCluster and AWSControlPlane CRs.
+type ControlPlaneDrift struct {
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+	// now is only meant to be replaced in tests.
+	now func() time.Time
+}
+
+// controlPlaneSpec is the desired state of a cluster's control plane as
+// described by its CRs.
+type controlPlaneSpec struct {
+	MasterAvailabilityZones []string
+	MasterInstanceType      string
+	PodCIDR                 string
+	Region                  string
+	// VPCCIDR is taken from the AWSCluster status, since the spec does not
+	// configure it. The aws-operator allocates the CIDR from the network pool
+	// of the installation and records it in the status.
+	VPCCIDR string
+}
+
+func NewControlPlaneDrift(config ControlPlaneDriftConfig) (*ControlPlaneDrift, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	c := &ControlPlaneDrift{
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+		now:              time.Now,
+	}
+
+	return c, nil
+}
+
+func (c *ControlPlaneDrift) Collect(ch chan<- prometheus.Metric) error {
+	ctx := context.Background()
+
+	d, err := c.helper.Discovery(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	controlPlanes := map[string]infrastructurev1alpha3.AWSControlPlane{}
+	for _, cp := range d.ControlPlanes.Items {
+		controlPlanes[cp.GetLabels()[label.Cluster]] = cp
+	}
+
+	clusters := map[string]infrastructurev1alpha3.AWSCluster{}
+	specs := map[string]controlPlaneSpec{}
+	for _, cluster := range d.Clusters.Items {
+		id := key.ClusterID(cluster)
+		clusters[id] = cluster
+
+		var cp *infrastructurev1alpha3.AWSControlPlane
+		if item, ok := controlPlanes[id]; ok {
+			cp = &item
+		}
+
+		specs[id] = newControlPlaneSpec(cluster, cp)
+	}
+
+	// A cluster without VPC might only look like one because the VPCs of its
+	// account or region could not be listed. Missing VPCs are therefore only
+	// reported if the VPCs of all accounts were listed, and only in the
+	// regions they were listed in.
+	var mutex sync.Mutex
+	complete := len(d.Accounts.Errors) == 0
+	found := map[string]bool{}
+	regions := map[string]bool{}
+
+	collectFunc := func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		ids, err := c.collectForAccount(ch, awsClients, accountID, specs)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err != nil {
+			complete = false
+			return microerror.Mask(err)
+		}
+		for _, id := range ids {
+			found[id] = true
+		}
+		regions[awsClients.Region] = true
+
+		return nil
+	}
+
+	err = c.helper.CollectForAccounts(ch, subsystemControlPlaneDrift, collectFunc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if complete {
+		for id, cluster := range clusters {
+			if found[id] {
+				continue
+			}
+
+			if !missingVPC(cluster, regions, c.now()) {
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				controlPlaneDriftMissingVPCDesc,
+				prometheus.GaugeValue,
+				GaugeValue,
+				d.Accounts.ClusterAccounts[id],
+				id,
+				cluster.Spec.Provider.Region,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (c *ControlPlaneDrift) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- controlPlaneDriftMismatchDesc
+	ch <- controlPlaneDriftMissingVPCDesc
+	return nil
+}
+
+// collectForAccount compares the VPCs and master instances of the
+// installation's clusters with the given specs, keyed by cluster ID. Clusters
+// are only reported in the account and region their VPC is found in. It
+// returns the IDs of the clusters it found a VPC for.
+func (c *ControlPlaneDrift) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string, specs map[string]controlPlaneSpec) ([]string, error) {
+	vpcs := map[string]*ec2.Vpc{}
+	{
+		i := &ec2.DescribeVpcsInput{
+			Filters: installationFilters(c.installationName),
+		}
+		for {
+			o, err := awsClients.EC2.DescribeVpcs(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			for _, vpc := range o.Vpcs {
+				id := ec2TagMap(vpc.Tags)[tagCluster]
+				if _, ok := specs[id]; ok {
+					vpcs[id] = vpc
+				}
+			}
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	// Most accounts and regions contain no clusters of the installation, in
+	// which case the master instances do not have to be listed.
+	if len(vpcs) == 0 {
+		return nil, nil
+	}
+
+	masters := map[string][]*ec2.Instance{}
+	{
+		i := &ec2.DescribeInstancesInput{
+			Filters: append(
+				installationFilters(c.installationName),
+				&ec2.Filter{
+					Name: aws.String(fmt.Sprintf("tag:%s", tagStack)),
+					Values: []*string{
+						aws.String(key.StackTCCPN),
+					},
+				},
+				&ec2.Filter{
+					Name: aws.String("instance-state-name"),
+					Values: []*string{
+						aws.String(ec2.InstanceStateNamePending),
+						aws.String(ec2.InstanceStateNameRunning),
+					},
+				},
+			),
+			MaxResults: aws.Int64(1000),
+		}
+		for {
+			o, err := awsClients.EC2.DescribeInstances(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			for _, reservation := range o.Reservations {
+				for _, instance := range reservation.Instances {
+					id := ec2TagMap(instance.Tags)[tagCluster]
+					masters[id] = append(masters[id], instance)
+				}
+			}
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	var found []string
+	for id, vpc := range vpcs {
+		found = append(found, id)
+
+		for field, drift := range controlPlaneDrift(specs[id], awsClients.Region, vpc, masters[id]) {
+			var value float64
+			if drift {
+				value = GaugeValue
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				controlPlaneDriftMismatchDesc,
+				prometheus.GaugeValue,
+				value,
+				accountID,
+				id,
+				field,
+				awsClients.Region,
+			)
+		}
+	}
+
+	return found, nil
+}
+
+// missingVPC returns whether the VPC of the given cluster, which was not found
+// in any of the given collected regions, is missing. The VPC can only be
+// missing if the region of the cluster was collected. Clusters being created
+// or deleted are never missing their VPC.
+func missingVPC(cluster infrastructurev1alpha3.AWSCluster, regions map[string]bool, now time.Time) bool {
+	if cluster.GetDeletionTimestamp() != nil {
+		return false
+	}
+	if now.Sub(cluster.GetCreationTimestamp().Time) < clusterCreationGracePeriod {
+		return false
+	}
+
+	region := cluster.Spec.Provider.Region
+	return region != "" && regions[region]
+}
+
+// newControlPlaneSpec returns the desired control plane of the given cluster.
+// Master settings are taken from the AWSControlPlane CR if the cluster has
+// one, and from the deprecated master settings of the AWSCluster CR otherwise.
+func newControlPlaneSpec(cluster infrastructurev1alpha3.AWSCluster, cp *infrastructurev1alpha3.AWSControlPlane) controlPlaneSpec {
+	spec := controlPlaneSpec{
+		PodCIDR: cluster.Spec.Provider.Pods.CIDRBlock,
+		Region:  cluster.Spec.Provider.Region,
+		VPCCIDR: cluster.Status.Provider.Network.CIDR,
+	}
+
+	if cp != nil {
+		spec.MasterAvailabilityZones = cp.Spec.AvailabilityZones
+		spec.MasterInstanceType = cp.Spec.InstanceType
+	}
+	if len(spec.MasterAvailabilityZones) == 0 && cluster.Spec.Provider.Master.AvailabilityZone != "" {
+		spec.MasterAvailabilityZones = []string{cluster.Spec.Provider.Master.AvailabilityZone}
+	}
+	if spec.MasterInstanceType == "" {
+		spec.MasterInstanceType = cluster.Spec.Provider.Master.InstanceType
+	}
+
+	return spec
+}
+
+// controlPlaneDrift returns for every compared field whether the given VPC and
+// master instances found in the given region differ from the given spec.
+// Fields which are not set in the spec can not drift. Neither can master
+// settings without master instances, e.g. while masters are replaced.
+func controlPlaneDrift(spec controlPlaneSpec, region string, vpc *ec2.Vpc, masters []*ec2.Instance) map[string]bool {
+	// The pod CIDR is associated with the VPC as secondary CIDR block.
+	var cidrs []string
+	for _, a := range vpc.CidrBlockAssociationSet {
+		if a.CidrBlockState != nil && aws.StringValue(a.CidrBlockState.State) != ec2.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		cidrs = append(cidrs, aws.StringValue(a.CidrBlock))
+	}
+
+	zones := map[string]bool{}
+	var types []string
+	for _, instance := range masters {
+		if instance.Placement != nil {
+			zones[aws.StringValue(instance.Placement.AvailabilityZone)] = true
+		}
+		if !containsString(types, aws.StringValue(instance.InstanceType)) {
+			types = append(types, aws.StringValue(instance.InstanceType))
+		}
+	}
+	var zoneList []string
+	for zone := range zones {
+		zoneList = append(zoneList, zone)
+	}
+
+	drift := map[string]bool{
+		driftFieldMasterAvailabilityZones: false,
+		driftFieldMasterInstanceType:      false,
+		driftFieldPodCIDR:                 spec.PodCIDR != "" && !containsString(cidrs, spec.PodCIDR),
+		driftFieldRegion:                  spec.Region != "" && spec.Region != region,
+		driftFieldVPCCIDR:                 spec.VPCCIDR != "" && spec.VPCCIDR != aws.StringValue(vpc.CidrBlock),
+	}
+
+	if len(masters) > 0 {
+		if len(spec.MasterAvailabilityZones) > 0 {
+			drift[driftFieldMasterAvailabilityZones] = !equalStringSets(spec.MasterAvailabilityZones, zoneList)
+		}
+		if spec.MasterInstanceType != "" {
+			drift[driftFieldMasterInstanceType] = len(types) != 1 || types[0] != spec.MasterInstanceType
+		}
+	}
+
+	return drift
+}
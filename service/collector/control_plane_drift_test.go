@@ -0,0 +1,332 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type controlPlaneEC2Mock struct {
+	ec2iface.EC2API
+
+	instances []*ec2.Instance
+	vpcs      []*ec2.Vpc
+}
+
+func (e *controlPlaneEC2Mock) DescribeInstances(i *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	o := &ec2.DescribeInstancesOutput{}
+	for _, instance := range e.instances {
+		if hasTags(instance.Tags, i.Filters) {
+			o.Reservations = append(o.Reservations, &ec2.Reservation{
+				Instances: []*ec2.Instance{instance},
+			})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *controlPlaneEC2Mock) DescribeVpcs(i *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	o := &ec2.DescribeVpcsOutput{}
+	for _, vpc := range e.vpcs {
+		if hasTags(vpc.Tags, i.Filters) {
+			o.Vpcs = append(o.Vpcs, vpc)
+		}
+	}
+
+	return o, nil
+}
+
+func testVPC(cluster string, cidrs ...string) *ec2.Vpc {
+	vpc := &ec2.Vpc{
+		CidrBlock: aws.String(cidrs[0]),
+		Tags:      ec2Tags("test", cluster),
+	}
+	for _, cidr := range cidrs {
+		vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, &ec2.VpcCidrBlockAssociation{
+			CidrBlock: aws.String(cidr),
+			CidrBlockState: &ec2.VpcCidrBlockState{
+				State: aws.String(ec2.VpcCidrBlockStateCodeAssociated),
+			},
+		})
+	}
+
+	return vpc
+}
+
+func testMaster(cluster string, instanceType string, zone string) *ec2.Instance {
+	return &ec2.Instance{
+		InstanceType: aws.String(instanceType),
+		Placement: &ec2.Placement{
+			AvailabilityZone: aws.String(zone),
+		},
+		Tags: append(
+			ec2Tags("test", cluster),
+			&ec2.Tag{Key: aws.String(tagStack), Value: aws.String(key.StackTCCPN)},
+		),
+	}
+}
+
+func Test_ControlPlaneDrift_collectForAccount(t *testing.T) {
+	spec := controlPlaneSpec{
+		MasterAvailabilityZones: []string{"eu-central-1a", "eu-central-1b", "eu-central-1c"},
+		MasterInstanceType:      "m5.xlarge",
+		PodCIDR:                 "100.64.0.0/16",
+		Region:                  "eu-central-1",
+		VPCCIDR:                 "10.1.0.0/24",
+	}
+
+	specs := map[string]controlPlaneSpec{
+		// The control plane of al9qy matches its CRs.
+		"al9qy": spec,
+		// The control plane of x7k2e lost its pod CIDR and a master, and
+		// another master was resized in the AWS console.
+		"x7k2e": spec,
+		// The VPC of b4c1d is not in the region of its CR. Its master
+		// settings can not drift while it has no masters.
+		"b4c1d": {
+			MasterAvailabilityZones: []string{"eu-central-1a"},
+			MasterInstanceType:      "m5.xlarge",
+			Region:                  "eu-west-1",
+		},
+		// Clusters without VPC in the account and region are not
+		// reported here.
+		"g5h6i": spec,
+	}
+
+	awsClients := clientaws.Clients{
+		EC2: &controlPlaneEC2Mock{
+			instances: []*ec2.Instance{
+				testMaster("al9qy", "m5.xlarge", "eu-central-1a"),
+				testMaster("al9qy", "m5.xlarge", "eu-central-1b"),
+				testMaster("al9qy", "m5.xlarge", "eu-central-1c"),
+				testMaster("x7k2e", "m5.xlarge", "eu-central-1a"),
+				testMaster("x7k2e", "m5.2xlarge", "eu-central-1b"),
+				// Workers are no masters.
+				{
+					InstanceType: aws.String("m5.4xlarge"),
+					Tags:         ec2Tags("test", "al9qy"),
+				},
+			},
+			vpcs: []*ec2.Vpc{
+				testVPC("al9qy", "10.1.0.0/24", "100.64.0.0/16"),
+				testVPC("x7k2e", "10.1.0.0/24"),
+				testVPC("b4c1d", "10.2.0.0/24"),
+				// VPCs of other installations are ignored.
+				{
+					CidrBlock: aws.String("10.1.0.0/24"),
+					Tags:      ec2Tags("other", "al9qy"),
+				},
+			},
+		},
+		Region: "eu-central-1",
+	}
+
+	c, err := NewControlPlaneDrift(ControlPlaneDriftConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			_, err := c.collectForAccount(ch, awsClients, "000000000000", specs)
+			return err
+		},
+		describe: c.Describe,
+	}
+
+	compareGolden(t, "control_plane_drift", gatherText(t, a))
+}
+
+func Test_ControlPlaneDrift_Collect_MissingVPC(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		accountErrors map[string]accountError
+		expected      []map[string]string
+	}{
+		{
+			name: "case 0: VPCs of all accounts listed",
+			expected: []map[string]string{
+				{
+					labelAccountID: "111111111111",
+					labelCluster:   "x7k2e",
+					labelRegion:    "eu-central-1",
+				},
+			},
+		},
+		{
+			name: "case 1: VPCs of an account could not be listed",
+			accountErrors: map[string]accountError{
+				"222222222222": {Err: errors.New("test error")},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHelper(t, fake.NewFakeClientWithScheme(newTestScheme(t)))
+			h.now = func() time.Time { return now }
+
+			var clusters []infrastructurev1alpha3.AWSCluster
+			for _, id := range []string{"al9qy", "x7k2e"} {
+				cluster := newTestCluster(id, "credential-first")
+				cluster.Spec.Provider.Region = "eu-central-1"
+				cluster.SetCreationTimestamp(metav1.NewTime(now.Add(-time.Hour)))
+				clusters = append(clusters, *cluster)
+			}
+
+			h.discovery = &discovery{
+				Accounts: &awsAccounts{
+					Clients: map[string][]clientaws.Clients{
+						"111111111111": {
+							{
+								EC2: &controlPlaneEC2Mock{
+									vpcs: []*ec2.Vpc{
+										testVPC("al9qy", "10.1.0.0/24"),
+									},
+								},
+								Region: "eu-central-1",
+							},
+						},
+					},
+					ClusterAccounts: map[string]string{
+						"al9qy": "111111111111",
+						"x7k2e": "111111111111",
+					},
+					Errors: tc.accountErrors,
+				},
+				Clusters: &infrastructurev1alpha3.AWSClusterList{
+					Items: clusters,
+				},
+				ControlPlanes: &infrastructurev1alpha3.AWSControlPlaneList{},
+				Time:          now,
+			}
+
+			c, err := NewControlPlaneDrift(ControlPlaneDriftConfig{
+				Helper: h,
+				Logger: microloggertest.New(),
+
+				InstallationName: "test",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.now = h.now
+
+			ch := make(chan prometheus.Metric, 100)
+			err = c.Collect(ch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			close(ch)
+
+			var missing []map[string]string
+			for m := range ch {
+				if m.Desc() != controlPlaneDriftMissingVPCDesc {
+					continue
+				}
+
+				var pb dto.Metric
+				err := m.Write(&pb)
+				if err != nil {
+					t.Fatal(err)
+				}
+				labels := map[string]string{}
+				for _, l := range pb.Label {
+					labels[l.GetName()] = l.GetValue()
+				}
+				missing = append(missing, labels)
+			}
+
+			if !cmp.Equal(missing, tc.expected) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expected, missing))
+			}
+		})
+	}
+}
+
+func Test_missingVPC(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	regions := map[string]bool{"eu-central-1": true}
+
+	testCases := []struct {
+		name     string
+		created  time.Time
+		deleted  bool
+		region   string
+		expected bool
+	}{
+		{
+			name:     "case 0: cluster in a collected region",
+			created:  now.Add(-time.Hour),
+			region:   "eu-central-1",
+			expected: true,
+		},
+		{
+			name:     "case 1: cluster in a region which was not collected",
+			created:  now.Add(-time.Hour),
+			region:   "us-east-1",
+			expected: false,
+		},
+		{
+			name:     "case 2: cluster without region",
+			created:  now.Add(-time.Hour),
+			region:   "",
+			expected: false,
+		},
+		{
+			name:     "case 3: cluster being created",
+			created:  now.Add(-time.Minute),
+			region:   "eu-central-1",
+			expected: false,
+		},
+		{
+			name:     "case 4: cluster being deleted",
+			created:  now.Add(-time.Hour),
+			deleted:  true,
+			region:   "eu-central-1",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newTestCluster("al9qy", "credential-first")
+			cluster.Spec.Provider.Region = tc.region
+			cluster.SetCreationTimestamp(metav1.NewTime(tc.created))
+			if tc.deleted {
+				deleted := metav1.NewTime(now)
+				cluster.SetDeletionTimestamp(&deleted)
+			}
+
+			missing := missingVPC(*cluster, regions, now)
+			if missing != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, missing)
+			}
+		})
+	}
+}
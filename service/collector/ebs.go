@@ -0,0 +1,263 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	labelAttachmentState = "attachment_state"
+	labelEncrypted       = "encrypted"
+	labelVolumeType      = "volume_type"
+)
+
+const (
+	subsystemEBS = "ebs"
+)
+
+const (
+	// gibibyte is the number of bytes in a GiB, the unit EBS volume sizes are
+	// given in.
+	gibibyte = 1 << 30
+)
+
+var (
+	ebsVolumeDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEBS, "volume_info"),
+		"EBS volume information.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelID,
+			labelInstallation,
+			labelOrganization,
+			labelAvailabilityZone,
+			labelState,
+			labelVolumeType,
+			labelEncrypted,
+			labelAttachmentState,
+			labelInstance,
+			labelRegion,
+		},
+		nil,
+	)
+	ebsVolumeSizeDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEBS, "volume_size_bytes"),
+		"Size of EBS volumes in bytes.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelID,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	ebsVolumeIOPSDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEBS, "volume_iops"),
+		"Provisioned or baseline IOPS of EBS volumes.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelID,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	ebsAvailableVolumesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEBS, "available_volumes"),
+		"Number of EBS volumes not attached to any instance, e.g. because they were leaked on cluster deletion.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelInstallation,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type EBSConfig struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+}
+
+// EBS collects metrics about the EBS volumes of the installation.
+type EBS struct {
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+}
+
+func NewEBS(config EBSConfig) (*EBS, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	e := &EBS{
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+	}
+
+	return e, nil
+}
+
+func (e *EBS) Collect(ch chan<- prometheus.Metric) error {
+	err := e.helper.CollectForAccounts(ch, subsystemEBS, e.collectForAccount)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (e *EBS) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- ebsVolumeDesc
+	ch <- ebsVolumeSizeDesc
+	ch <- ebsVolumeIOPSDesc
+	ch <- ebsAvailableVolumesDesc
+	return nil
+}
+
+func (e *EBS) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	var volumes []*ec2.Volume
+	{
+		i := &ec2.DescribeVolumesInput{
+			Filters: []*ec2.Filter{
+				{
+					Name: aws.String(fmt.Sprintf("tag:%s", key.TagInstallation)),
+					Values: []*string{
+						aws.String(e.installationName),
+					},
+				},
+			},
+			MaxResults: aws.Int64(500),
+		}
+
+		for {
+			o, err := awsClients.EC2.DescribeVolumes(i)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			volumes = append(volumes, o.Volumes...)
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	// availableByCluster holds the number of unattached volumes per cluster.
+	// Clusters only having attached volumes are reported with 0.
+	availableByCluster := map[string]float64{}
+
+	for _, v := range volumes {
+		var cluster, organization string
+		for _, tag := range v.Tags {
+			switch aws.StringValue(tag.Key) {
+			case tagCluster:
+				cluster = aws.StringValue(tag.Value)
+			case tagOrganization:
+				organization = aws.StringValue(tag.Value)
+			}
+		}
+
+		var attachmentState, instanceID string
+		if len(v.Attachments) > 0 {
+			attachmentState = aws.StringValue(v.Attachments[0].State)
+			instanceID = aws.StringValue(v.Attachments[0].InstanceId)
+		}
+
+		state := aws.StringValue(v.State)
+		if state == ec2.VolumeStateAvailable {
+			availableByCluster[cluster]++
+		} else if _, ok := availableByCluster[cluster]; !ok {
+			availableByCluster[cluster] = 0
+		}
+
+		volumeID := aws.StringValue(v.VolumeId)
+
+		ch <- prometheus.MustNewConstMetric(
+			ebsVolumeDesc,
+			prometheus.GaugeValue,
+			GaugeValue,
+			accountID,
+			cluster,
+			volumeID,
+			e.installationName,
+			organization,
+			aws.StringValue(v.AvailabilityZone),
+			state,
+			aws.StringValue(v.VolumeType),
+			strconv.FormatBool(aws.BoolValue(v.Encrypted)),
+			attachmentState,
+			instanceID,
+			awsClients.Region,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			ebsVolumeSizeDesc,
+			prometheus.GaugeValue,
+			float64(aws.Int64Value(v.Size)*gibibyte),
+			accountID,
+			cluster,
+			volumeID,
+			e.installationName,
+			organization,
+			awsClients.Region,
+		)
+
+		if v.Iops != nil {
+			ch <- prometheus.MustNewConstMetric(
+				ebsVolumeIOPSDesc,
+				prometheus.GaugeValue,
+				float64(aws.Int64Value(v.Iops)),
+				accountID,
+				cluster,
+				volumeID,
+				e.installationName,
+				organization,
+				awsClients.Region,
+			)
+		}
+	}
+
+	for cluster, count := range availableByCluster {
+		ch <- prometheus.MustNewConstMetric(
+			ebsAvailableVolumesDesc,
+			prometheus.GaugeValue,
+			count,
+			accountID,
+			cluster,
+			e.installationName,
+			awsClients.Region,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type ebsMock struct {
+	ec2iface.EC2API
+
+	volumes []*ec2.Volume
+}
+
+// DescribeVolumes applies the tag filter of the given input, like the AWS API
+// does.
+func (e *ebsMock) DescribeVolumes(i *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	o := &ec2.DescribeVolumesOutput{}
+	for _, v := range e.volumes {
+		if hasTags(v.Tags, i.Filters) {
+			o.Volumes = append(o.Volumes, v)
+		}
+	}
+
+	return o, nil
+}
+
+// hasTags returns whether the given tags match all tag filters. Other filters
+// are ignored.
+func hasTags(tags []*ec2.Tag, filters []*ec2.Filter) bool {
+	for _, f := range filters {
+		if !strings.HasPrefix(aws.StringValue(f.Name), "tag:") {
+			continue
+		}
+
+		var found bool
+		for _, t := range tags {
+			if "tag:"+aws.StringValue(t.Key) == aws.StringValue(f.Name) && aws.StringValue(t.Value) == aws.StringValue(f.Values[0]) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ec2Tags(installation string, cluster string) []*ec2.Tag {
+	return []*ec2.Tag{
+		{Key: aws.String(key.TagInstallation), Value: aws.String(installation)},
+		{Key: aws.String(tagCluster), Value: aws.String(cluster)},
+		{Key: aws.String(tagOrganization), Value: aws.String("giantswarm")},
+	}
+}
+
+func Test_EBS_collectForAccount(t *testing.T) {
+	mock := &ebsMock{
+		volumes: []*ec2.Volume{
+			{
+				Attachments: []*ec2.VolumeAttachment{
+					{InstanceId: aws.String("i-000001"), State: aws.String(ec2.VolumeAttachmentStateAttached)},
+				},
+				AvailabilityZone: aws.String("eu-central-1a"),
+				Encrypted:        aws.Bool(true),
+				Iops:             aws.Int64(300),
+				Size:             aws.Int64(100),
+				State:            aws.String(ec2.VolumeStateInUse),
+				Tags:             ec2Tags("test", "al9qy"),
+				VolumeId:         aws.String("vol-000001"),
+				VolumeType:       aws.String(ec2.VolumeTypeGp2),
+			},
+			{
+				AvailabilityZone: aws.String("eu-central-1b"),
+				Encrypted:        aws.Bool(true),
+				Iops:             aws.Int64(3000),
+				Size:             aws.Int64(50),
+				State:            aws.String(ec2.VolumeStateAvailable),
+				Tags:             ec2Tags("test", "al9qy"),
+				VolumeId:         aws.String("vol-000002"),
+				VolumeType:       aws.String(ec2.VolumeTypeGp3),
+			},
+			// Volumes of deleted clusters are left over unattached.
+			{
+				AvailabilityZone: aws.String("eu-central-1a"),
+				Encrypted:        aws.Bool(false),
+				Size:             aws.Int64(10),
+				State:            aws.String(ec2.VolumeStateAvailable),
+				Tags:             ec2Tags("test", "x7k2e"),
+				VolumeId:         aws.String("vol-000003"),
+				VolumeType:       aws.String(ec2.VolumeTypeStandard),
+			},
+			// Volumes of other installations are ignored.
+			{
+				AvailabilityZone: aws.String("eu-central-1a"),
+				Size:             aws.Int64(10),
+				State:            aws.String(ec2.VolumeStateAvailable),
+				Tags:             ec2Tags("other", "b4c1d"),
+				VolumeId:         aws.String("vol-000004"),
+				VolumeType:       aws.String(ec2.VolumeTypeGp2),
+			},
+		},
+	}
+
+	e, err := NewEBS(EBSConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsClients := clientaws.Clients{
+		EC2:    mock,
+		Region: "eu-central-1",
+	}
+
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			return e.collectForAccount(ch, awsClients, "000000000000")
+		},
+		describe: e.Describe,
+	}
+
+	compareGolden(t, "ebs_volumes", gatherText(t, c))
+}
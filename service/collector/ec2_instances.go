@@ -1,7 +1,6 @@
 package collector
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
@@ -10,7 +9,6 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/controller/key"
@@ -58,6 +56,7 @@ var (
 			labelInstanceState,
 			labelInstanceStatus,
 			labelInstanceLifecycle,
+			labelRegion,
 		},
 		nil,
 	)
@@ -104,32 +103,7 @@ func NewEC2Instances(config EC2InstancesConfig) (*EC2Instances, error) {
 
 // Collect is the main metrics collection function.
 func (e *EC2Instances) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := e.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := e.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := e.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := e.helper.CollectForAccounts(ch, subsystemEC2, e.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -148,12 +122,7 @@ func (e *EC2Instances) Describe(ch chan<- *prometheus.Desc) error {
 // We gather two separate collections first, then match them by instance ID:
 // - instance information, including tags, only for those tagged for our installation
 // - instance status information
-func (e *EC2Instances) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	account, err := e.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+func (e *EC2Instances) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
 	// Collect instance status info.
 	// map key will be the instance ID.
 	instanceStatuses := map[string]*ec2.InstanceStatus{}
@@ -261,7 +230,7 @@ func (e *EC2Instances) collectForAccount(ch chan<- prometheus.Metric, awsClients
 			prometheus.GaugeValue,
 			float64(up),
 			instanceID,
-			account,
+			accountID,
 			cluster,
 			installation,
 			organization,
@@ -271,6 +240,7 @@ func (e *EC2Instances) collectForAccount(ch chan<- prometheus.Metric, awsClients
 			state,
 			status,
 			lifecycle,
+			awsClients.Region,
 		)
 	}
 
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	labelAssociated = "associated"
+	labelPublicIP   = "public_ip"
+)
+
+const (
+	subsystemEIP = "eip"
+)
+
+var (
+	eipAddressDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEIP, "address_info"),
+		"Elastic IP address information.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelID,
+			labelInstallation,
+			labelOrganization,
+			labelPublicIP,
+			labelAssociated,
+			labelInstance,
+			labelRegion,
+		},
+		nil,
+	)
+	eipAddressesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEIP, "addresses"),
+		"Number of Elastic IP addresses allocated in the account.",
+		[]string{
+			labelAccountID,
+			labelRegion,
+		},
+		nil,
+	)
+	eipUnassociatedAddressesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemEIP, "unassociated_addresses"),
+		"Number of Elastic IP addresses allocated in the account but not associated with any instance or network interface.",
+		[]string{
+			labelAccountID,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type EIPConfig struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+}
+
+// EIP collects metrics about the Elastic IP addresses of the accounts. The
+// address counts cover all addresses of an account, since all of them are
+// billed and counted against the same quota, while only the addresses of the
+// installation are reported individually.
+type EIP struct {
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+}
+
+func NewEIP(config EIPConfig) (*EIP, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	e := &EIP{
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+	}
+
+	return e, nil
+}
+
+func (e *EIP) Collect(ch chan<- prometheus.Metric) error {
+	err := e.helper.CollectForAccounts(ch, subsystemEIP, e.collectForAccount)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (e *EIP) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- eipAddressDesc
+	ch <- eipAddressesDesc
+	ch <- eipUnassociatedAddressesDesc
+	return nil
+}
+
+func (e *EIP) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	// DescribeAddresses is not paginated and returns all addresses at once.
+	var addresses []*ec2.Address
+	{
+		i := &ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("domain"),
+					Values: []*string{aws.String(ec2.DomainTypeVpc)},
+				},
+			},
+		}
+
+		o, err := awsClients.EC2.DescribeAddresses(i)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		addresses = o.Addresses
+	}
+
+	var unassociated float64
+	for _, a := range addresses {
+		associated := a.AssociationId != nil
+		if !associated {
+			unassociated++
+		}
+
+		var cluster, installation, organization string
+		for _, tag := range a.Tags {
+			switch aws.StringValue(tag.Key) {
+			case key.TagInstallation:
+				installation = aws.StringValue(tag.Value)
+			case tagCluster:
+				cluster = aws.StringValue(tag.Value)
+			case tagOrganization:
+				organization = aws.StringValue(tag.Value)
+			}
+		}
+
+		if installation != e.installationName {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			eipAddressDesc,
+			prometheus.GaugeValue,
+			GaugeValue,
+			accountID,
+			cluster,
+			aws.StringValue(a.AllocationId),
+			installation,
+			organization,
+			aws.StringValue(a.PublicIp),
+			strconv.FormatBool(associated),
+			aws.StringValue(a.InstanceId),
+			awsClients.Region,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		eipAddressesDesc,
+		prometheus.GaugeValue,
+		float64(len(addresses)),
+		accountID,
+		awsClients.Region,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		eipUnassociatedAddressesDesc,
+		prometheus.GaugeValue,
+		unassociated,
+		accountID,
+		awsClients.Region,
+	)
+
+	return nil
+}
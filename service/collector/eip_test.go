@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+type eipMock struct {
+	ec2iface.EC2API
+
+	addresses []*ec2.Address
+}
+
+func (e *eipMock) DescribeAddresses(*ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+	return &ec2.DescribeAddressesOutput{Addresses: e.addresses}, nil
+}
+
+func Test_EIP_collectForAccount(t *testing.T) {
+	mock := &eipMock{
+		addresses: []*ec2.Address{
+			// Address of a NAT gateway.
+			{
+				AllocationId:       aws.String("eipalloc-000001"),
+				AssociationId:      aws.String("eipassoc-000001"),
+				NetworkInterfaceId: aws.String("eni-000001"),
+				PublicIp:           aws.String("198.51.100.1"),
+				Tags:               ec2Tags("test", "al9qy"),
+			},
+			// Address of a bastion.
+			{
+				AllocationId:  aws.String("eipalloc-000002"),
+				AssociationId: aws.String("eipassoc-000002"),
+				InstanceId:    aws.String("i-000001"),
+				PublicIp:      aws.String("198.51.100.2"),
+				Tags:          ec2Tags("test", "al9qy"),
+			},
+			// Address left over after cluster deletion.
+			{
+				AllocationId: aws.String("eipalloc-000003"),
+				PublicIp:     aws.String("198.51.100.3"),
+				Tags:         ec2Tags("test", "x7k2e"),
+			},
+			// Addresses of other installations or without tags are only
+			// counted.
+			{
+				AllocationId: aws.String("eipalloc-000004"),
+				PublicIp:     aws.String("198.51.100.4"),
+				Tags:         ec2Tags("other", "b4c1d"),
+			},
+			{
+				AllocationId:  aws.String("eipalloc-000005"),
+				AssociationId: aws.String("eipassoc-000005"),
+				PublicIp:      aws.String("198.51.100.5"),
+			},
+		},
+	}
+
+	e, err := NewEIP(EIPConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsClients := clientaws.Clients{
+		EC2:    mock,
+		Region: "eu-central-1",
+	}
+
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			return e.collectForAccount(ch, awsClients, "000000000000")
+		},
+		describe: e.Describe,
+	}
+
+	compareGolden(t, "eip_addresses", gatherText(t, c))
+}
@@ -3,9 +3,11 @@ package collector
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
@@ -30,7 +32,25 @@ const (
 )
 
 const (
+	labelReason     = "reason"
+	labelReasonCode = "reason_code"
+)
+
+const (
+	stateInService    = "InService"
 	stateOutOfService = "OutOfService"
+	stateUnknown      = "Unknown"
+)
+
+const (
+	// The reasons categorize the descriptions AWS gives for the health state
+	// of an instance, since the descriptions themselves are free text.
+	reasonDeregistration     = "deregistration_in_progress"
+	reasonHealthCheckFailed  = "health_check_failed"
+	reasonInstanceNotRunning = "instance_not_running"
+	reasonNone               = "none"
+	reasonOther              = "other"
+	reasonRegistration       = "registration_in_progress"
 )
 
 var (
@@ -43,6 +63,38 @@ var (
 			labelCluster,
 			labelInstallation,
 			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	elbInstancesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemELB, "instances"),
+		"Gauge about the number of ELB instances by health state.",
+		[]string{
+			labelELB,
+			labelState,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	elbInstanceHealthDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemELB, "instance_health"),
+		"Gauge about the health state of every ELB instance together with the reason AWS gives for it.",
+		[]string{
+			labelELB,
+			labelInstance,
+			labelState,
+			labelReasonCode,
+			labelReason,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
 		},
 		nil,
 	)
@@ -72,9 +124,16 @@ type elbInfoResponse struct {
 }
 
 type elbInfo struct {
-	InstancesOutOfService float64
-	Name                  string
-	Tags                  map[string]string
+	Instances []elbInstanceInfo
+	Name      string
+	Tags      map[string]string
+}
+
+type elbInstanceInfo struct {
+	ID         string
+	Reason     string
+	ReasonCode string
+	State      string
 }
 
 func NewELB(config ELBConfig) (*ELB, error) {
@@ -108,61 +167,42 @@ func newELBCache(expiration time.Duration) *elbCache {
 	return cache
 }
 
-func (n *elbCache) Get(key string) (*elbInfoResponse, error) {
+// Get returns the cached ELB info of the given account and region, or nil if
+// there is none.
+func (n *elbCache) Get(accountID string, region string) (*elbInfoResponse, error) {
+	raw, exists := n.cache.Get(getELBCacheKey(accountID, region))
+	if !exists {
+		return nil, nil
+	}
+
 	var c elbInfoResponse
-	raw, exists := n.cache.Get(getELBCacheKey(key))
-	if exists {
-		err := json.Unmarshal(raw, &c)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+	err := json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
 	return &c, nil
 }
 
-func (n *elbCache) Set(key string, content elbInfoResponse) error {
+func (n *elbCache) Set(accountID string, region string, content elbInfoResponse) error {
 	contentSerialized, err := json.Marshal(content)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	n.cache.Set(getELBCacheKey(key), contentSerialized)
+	n.cache.Set(getELBCacheKey(accountID, region), contentSerialized)
 
 	return nil
 }
 
-func getELBCacheKey(key string) string {
-	return prefixELBcacheKey + key
+func getELBCacheKey(accountID string, region string) string {
+	return prefixELBcacheKey + accountID + "/" + region
 }
 
 func (e *ELB) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := e.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := e.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := e.collectForAccount(context.Background(), ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := e.helper.CollectForAccounts(ch, subsystemELB, func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		return e.collectForAccount(context.Background(), ch, awsClients, accountID)
+	})
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -172,50 +212,91 @@ func (e *ELB) Collect(ch chan<- prometheus.Metric) error {
 
 func (e *ELB) Describe(ch chan<- *prometheus.Desc) error {
 	ch <- elbsDesc
+	ch <- elbInstancesDesc
+	ch <- elbInstanceHealthDesc
 	return nil
 }
 
-func (e *ELB) collectForAccount(ctx context.Context, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	account, err := e.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+func (e *ELB) collectForAccount(ctx context.Context, ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
 	var elbInfo *elbInfoResponse
 	// Check if response is cached
-	elbInfo, err = e.cache.Get(account)
+	elbInfo, err := e.cache.Get(accountID, awsClients.Region)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	//Cache empty, getting from API
-	if elbInfo == nil || elbInfo.Elbs == nil {
-		elbInfo, err = getElbInfoFromAPI(ctx, account, e.installationName, awsClients)
+	// Cache empty, getting from API. Accounts and regions without load
+	// balancers are cached as well.
+	if elbInfo == nil {
+		elbInfo, err = getElbInfoFromAPI(ctx, accountID, e.installationName, awsClients)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
-		if elbInfo != nil {
-			err = e.cache.Set(account, *elbInfo)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		err = e.cache.Set(accountID, awsClients.Region, *elbInfo)
+		if err != nil {
+			return microerror.Mask(err)
 		}
 	}
 
-	if elbInfo != nil {
-		for _, lb := range elbInfo.Elbs {
+	for _, lb := range elbInfo.Elbs {
+		// Instances in states unknown to us are counted as unknown.
+		states := map[string]float64{
+			stateInService:    0,
+			stateOutOfService: 0,
+			stateUnknown:      0,
+		}
+
+		for _, instance := range lb.Instances {
+			state := instance.State
+			if _, ok := states[state]; !ok {
+				state = stateUnknown
+			}
+			states[state]++
+
 			ch <- prometheus.MustNewConstMetric(
-				elbsDesc,
+				elbInstanceHealthDesc,
 				prometheus.GaugeValue,
-				lb.InstancesOutOfService,
+				GaugeValue,
 				lb.Name,
-				account,
+				instance.ID,
+				instance.State,
+				instance.ReasonCode,
+				instance.Reason,
+				accountID,
 				lb.Tags[tagCluster],
 				lb.Tags[key.TagInstallation],
 				lb.Tags[tagOrganization],
+				awsClients.Region,
 			)
 		}
+
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(
+				elbInstancesDesc,
+				prometheus.GaugeValue,
+				count,
+				lb.Name,
+				state,
+				accountID,
+				lb.Tags[tagCluster],
+				lb.Tags[key.TagInstallation],
+				lb.Tags[tagOrganization],
+				awsClients.Region,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			elbsDesc,
+			prometheus.GaugeValue,
+			states[stateOutOfService],
+			lb.Name,
+			accountID,
+			lb.Tags[tagCluster],
+			lb.Tags[key.TagInstallation],
+			lb.Tags[tagOrganization],
+			awsClients.Region,
+		)
 	}
 
 	return nil
@@ -228,19 +309,26 @@ func getElbInfoFromAPI(ctx context.Context, account string, installation string,
 	var loadBalancerNames []*string
 	{
 		i := &elb.DescribeLoadBalancersInput{}
-		o, err := awsClients.ELB.DescribeLoadBalancers(i)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		for _, d := range o.LoadBalancerDescriptions {
-			loadBalancerNames = append(loadBalancerNames, d.LoadBalancerName)
+		for {
+			o, err := awsClients.ELB.DescribeLoadBalancers(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			for _, d := range o.LoadBalancerDescriptions {
+				loadBalancerNames = append(loadBalancerNames, d.LoadBalancerName)
+			}
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
 		}
 
 		if len(loadBalancerNames) == 0 {
 			// E.g. during cluster creation there are no load balancers present
 			// yet so further AWS API calls would fail on validation. No
 			// metrics to emit either so we can short circuit here.
-			return nil, nil
+			return &res, nil
 		}
 	}
 
@@ -312,20 +400,25 @@ func getElbInfoFromAPI(ctx context.Context, account string, installation string,
 	{
 		// AWS API doesn't provide a method to describe instance health for all
 		// specified ELBs so it must be done with N API calls.
-		for _, lb := range lbs {
-			i := &elb.DescribeInstanceHealthInput{
-				LoadBalancerName: &lb.Name,
+		for i := range lbs {
+			input := &elb.DescribeInstanceHealthInput{
+				LoadBalancerName: aws.String(lbs[i].Name),
 			}
 
-			o, err := awsClients.ELB.DescribeInstanceHealth(i)
+			o, err := awsClients.ELB.DescribeInstanceHealth(input)
 			if err != nil {
 				return nil, microerror.Mask(err)
 			}
 
 			for _, s := range o.InstanceStates {
-				if *s.State == stateOutOfService {
-					lb.InstancesOutOfService++
+				instance := elbInstanceInfo{
+					ID:         aws.StringValue(s.InstanceId),
+					Reason:     elbInstanceReason(aws.StringValue(s.Description)),
+					ReasonCode: aws.StringValue(s.ReasonCode),
+					State:      aws.StringValue(s.State),
 				}
+
+				lbs[i].Instances = append(lbs[i].Instances, instance)
 			}
 		}
 	}
@@ -333,3 +426,25 @@ func getElbInfoFromAPI(ctx context.Context, account string, installation string,
 
 	return &res, nil
 }
+
+// elbInstanceReason categorizes the description AWS gives for the health state
+// of an ELB instance, see
+// https://docs.aws.amazon.com/elasticloadbalancing/2012-06-01/APIReference/API_InstanceState.html.
+func elbInstanceReason(description string) string {
+	switch {
+	case description == "" || description == "N/A":
+		return reasonNone
+	case strings.Contains(description, "failed at least the UnhealthyThreshold"):
+		return reasonHealthCheckFailed
+	case strings.Contains(description, "registration is still in progress"):
+		return reasonRegistration
+	case strings.Contains(description, "deregistration currently in progress"):
+		return reasonDeregistration
+	case strings.Contains(description, "is in stopped state"),
+		strings.Contains(description, "is in terminated state"),
+		strings.Contains(description, "is not running"):
+		return reasonInstanceNotRunning
+	}
+
+	return reasonOther
+}
@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type elbMock struct {
+	elbiface.ELBAPI
+
+	// instanceStates and tags are keyed by load balancer name.
+	instanceStates map[string][]*elb.InstanceState
+	tags           map[string][]*elb.Tag
+
+	describeCalls int
+}
+
+func (e *elbMock) DescribeLoadBalancers(*elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	e.describeCalls++
+
+	o := &elb.DescribeLoadBalancersOutput{}
+	for name := range e.tags {
+		o.LoadBalancerDescriptions = append(o.LoadBalancerDescriptions, &elb.LoadBalancerDescription{
+			LoadBalancerName: aws.String(name),
+		})
+	}
+
+	return o, nil
+}
+
+func (e *elbMock) DescribeTags(i *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
+	o := &elb.DescribeTagsOutput{}
+	for _, name := range i.LoadBalancerNames {
+		o.TagDescriptions = append(o.TagDescriptions, &elb.TagDescription{
+			LoadBalancerName: name,
+			Tags:             e.tags[*name],
+		})
+	}
+
+	return o, nil
+}
+
+func (e *elbMock) DescribeInstanceHealth(i *elb.DescribeInstanceHealthInput) (*elb.DescribeInstanceHealthOutput, error) {
+	return &elb.DescribeInstanceHealthOutput{InstanceStates: e.instanceStates[*i.LoadBalancerName]}, nil
+}
+
+func instanceState(id string, state string, reasonCode string, description string) *elb.InstanceState {
+	return &elb.InstanceState{
+		Description: aws.String(description),
+		InstanceId:  aws.String(id),
+		ReasonCode:  aws.String(reasonCode),
+		State:       aws.String(state),
+	}
+}
+
+func elbTags(installation string, cluster string) []*elb.Tag {
+	return []*elb.Tag{
+		{Key: aws.String(key.TagInstallation), Value: aws.String(installation)},
+		{Key: aws.String(tagCluster), Value: aws.String(cluster)},
+		{Key: aws.String(tagOrganization), Value: aws.String("giantswarm")},
+	}
+}
+
+func Test_ELB_collectForAccount(t *testing.T) {
+	testCases := []struct {
+		name   string
+		golden string
+		mock   *elbMock
+	}{
+		{
+			name:   "case 0: instances in every health state",
+			golden: "elb_health_states",
+			mock: &elbMock{
+				instanceStates: map[string][]*elb.InstanceState{
+					"al9qy-api": {
+						instanceState("i-000001", stateInService, "N/A", "N/A"),
+						instanceState("i-000002", stateOutOfService, "Instance", "Instance has failed at least the UnhealthyThreshold number of health checks consecutively."),
+						instanceState("i-000003", stateOutOfService, "ELB", "Instance registration is still in progress."),
+						instanceState("i-000004", stateUnknown, "ELB", "A transient error occurred. Please try again later."),
+					},
+					"al9qy-ingress": {
+						instanceState("i-000001", stateInService, "N/A", "N/A"),
+						instanceState("i-000005", stateOutOfService, "Instance", "Instance is in stopped state."),
+					},
+				},
+				tags: map[string][]*elb.Tag{
+					"al9qy-api":     elbTags("test", "al9qy"),
+					"al9qy-ingress": elbTags("test", "al9qy"),
+					// Load balancers of other installations are ignored.
+					"x7k2e-api": elbTags("other", "x7k2e"),
+				},
+			},
+		},
+		{
+			name:   "case 1: load balancer without instances",
+			golden: "elb_no_instances",
+			mock: &elbMock{
+				tags: map[string][]*elb.Tag{
+					"al9qy-api": elbTags("test", "al9qy"),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewELB(ELBConfig{
+				Helper: &helper{},
+				Logger: microloggertest.New(),
+
+				InstallationName: "test",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			awsClients := clientaws.Clients{
+				ELB:    tc.mock,
+				Region: "eu-central-1",
+			}
+
+			c := accountCollectorFunc{
+				t: t,
+
+				collect: func(ch chan<- prometheus.Metric) error {
+					return e.collectForAccount(context.Background(), ch, awsClients, "000000000000")
+				},
+				describe: e.Describe,
+			}
+
+			compareGolden(t, tc.golden, gatherText(t, c))
+		})
+	}
+}
+
+func Test_ELB_collectForAccount_cache(t *testing.T) {
+	e, err := NewELB(ELBConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &elbMock{}
+	awsClients := clientaws.Clients{
+		ELB:    mock,
+		Region: "eu-central-1",
+	}
+
+	// Regions without load balancers are cached as well, so the second
+	// collection does not list the load balancers again.
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		err := e.collectForAccount(context.Background(), ch, awsClients, "000000000000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ch) != 0 {
+			t.Fatalf("expected no metrics, got %d", len(ch))
+		}
+	}
+
+	if mock.describeCalls != 1 {
+		t.Fatalf("expected 1 DescribeLoadBalancers call, got %d", mock.describeCalls)
+	}
+}
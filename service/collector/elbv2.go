@@ -0,0 +1,429 @@
+package collector
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+	"github.com/giantswarm/aws-collector/service/internal/cache"
+)
+
+const (
+	// __ELBv2Cache__ is used as temporal cache key to save ELBv2 response.
+	prefixELBv2cacheKey   = "__ELBv2Cache__"
+	labelLoadBalancer     = "load_balancer"
+	labelLoadBalancerType = "type"
+	labelTargetGroup      = "target_group"
+	// maxELBv2sInOneDescribeTagsBatch - https://docs.aws.amazon.com/elasticloadbalancing/latest/APIReference/API_DescribeTags.html
+	maxELBv2sInOneDescribeTagsBatch = 20
+)
+
+const (
+	subsystemELBv2 = "elbv2"
+)
+
+var (
+	elbv2StateDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemELBv2, "load_balancer_state"),
+		"Gauge about the state of application and network load balancers.",
+		[]string{
+			labelLoadBalancer,
+			labelLoadBalancerType,
+			labelState,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	elbv2ListenersDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemELBv2, "listeners"),
+		"Gauge about the number of listeners of application and network load balancers.",
+		[]string{
+			labelLoadBalancer,
+			labelLoadBalancerType,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+	elbv2TargetsDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemELBv2, "target_group_targets"),
+		"Gauge about the number of targets of load balancer target groups by health state.",
+		[]string{
+			labelLoadBalancer,
+			labelTargetGroup,
+			labelState,
+			labelAccount,
+			labelCluster,
+			labelInstallation,
+			labelOrganization,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type ELBv2Config struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+}
+
+// ELBv2 collects metrics about application and network load balancers.
+type ELBv2 struct {
+	cache  *elbv2Cache
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+}
+
+type elbv2Cache struct {
+	cache *cache.StringCache
+}
+
+type elbv2InfoResponse struct {
+	LoadBalancers []elbv2Info
+}
+
+type elbv2Info struct {
+	Listeners    float64
+	Name         string
+	State        string
+	Tags         map[string]string
+	TargetGroups []elbv2TargetGroupInfo
+	Type         string
+}
+
+type elbv2TargetGroupInfo struct {
+	Name string
+	// Targets holds the number of targets keyed by their health state.
+	Targets map[string]float64
+}
+
+func NewELBv2(config ELBv2Config) (*ELBv2, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	e := &ELBv2{
+		cache:  newELBv2Cache(time.Minute * 5),
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+	}
+
+	return e, nil
+}
+
+func newELBv2Cache(expiration time.Duration) *elbv2Cache {
+	cache := &elbv2Cache{
+		cache: cache.NewStringCache(expiration),
+	}
+
+	return cache
+}
+
+// Get returns the cached ELBv2 info of the given account and region, or nil if
+// there is none.
+func (n *elbv2Cache) Get(accountID string, region string) (*elbv2InfoResponse, error) {
+	raw, exists := n.cache.Get(getELBv2CacheKey(accountID, region))
+	if !exists {
+		return nil, nil
+	}
+
+	var c elbv2InfoResponse
+	err := json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return &c, nil
+}
+
+func (n *elbv2Cache) Set(accountID string, region string, content elbv2InfoResponse) error {
+	contentSerialized, err := json.Marshal(content)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	n.cache.Set(getELBv2CacheKey(accountID, region), contentSerialized)
+
+	return nil
+}
+
+func getELBv2CacheKey(accountID string, region string) string {
+	return prefixELBv2cacheKey + accountID + "/" + region
+}
+
+func (e *ELBv2) Collect(ch chan<- prometheus.Metric) error {
+	err := e.helper.CollectForAccounts(ch, subsystemELBv2, e.collectForAccount)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (e *ELBv2) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- elbv2StateDesc
+	ch <- elbv2ListenersDesc
+	ch <- elbv2TargetsDesc
+	return nil
+}
+
+func (e *ELBv2) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	// Check if response is cached
+	info, err := e.cache.Get(accountID, awsClients.Region)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Cache empty, getting from API. Accounts and regions without load
+	// balancers are cached as well.
+	if info == nil {
+		info, err = getELBv2InfoFromAPI(e.installationName, awsClients)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = e.cache.Set(accountID, awsClients.Region, *info)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, lb := range info.LoadBalancers {
+		ch <- prometheus.MustNewConstMetric(
+			elbv2StateDesc,
+			prometheus.GaugeValue,
+			GaugeValue,
+			lb.Name,
+			lb.Type,
+			lb.State,
+			accountID,
+			lb.Tags[tagCluster],
+			lb.Tags[key.TagInstallation],
+			lb.Tags[tagOrganization],
+			awsClients.Region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			elbv2ListenersDesc,
+			prometheus.GaugeValue,
+			lb.Listeners,
+			lb.Name,
+			lb.Type,
+			accountID,
+			lb.Tags[tagCluster],
+			lb.Tags[key.TagInstallation],
+			lb.Tags[tagOrganization],
+			awsClients.Region,
+		)
+
+		for _, tg := range lb.TargetGroups {
+			for state, count := range tg.Targets {
+				ch <- prometheus.MustNewConstMetric(
+					elbv2TargetsDesc,
+					prometheus.GaugeValue,
+					count,
+					lb.Name,
+					tg.Name,
+					state,
+					accountID,
+					lb.Tags[tagCluster],
+					lb.Tags[key.TagInstallation],
+					lb.Tags[tagOrganization],
+					awsClients.Region,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getELBv2InfoFromAPI collects the load balancers of the installation together
+// with their listeners and target groups from the AWS API.
+func getELBv2InfoFromAPI(installation string, awsClients clientaws.Clients) (*elbv2InfoResponse, error) {
+	// The response is never nil, even without load balancers, so that empty
+	// responses are cached as well.
+	res := &elbv2InfoResponse{
+		LoadBalancers: []elbv2Info{},
+	}
+
+	var loadBalancers []*elbv2.LoadBalancer
+	{
+		i := &elbv2.DescribeLoadBalancersInput{}
+		for {
+			o, err := awsClients.ELBv2.DescribeLoadBalancers(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			loadBalancers = append(loadBalancers, o.LoadBalancers...)
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
+		}
+	}
+
+	// Tags can only be described for a limited number of load balancers at
+	// once.
+	tags := map[string]map[string]string{}
+	for start := 0; start < len(loadBalancers); start += maxELBv2sInOneDescribeTagsBatch {
+		end := start + maxELBv2sInOneDescribeTagsBatch
+		if end > len(loadBalancers) {
+			end = len(loadBalancers)
+		}
+
+		i := &elbv2.DescribeTagsInput{}
+		for _, lb := range loadBalancers[start:end] {
+			i.ResourceArns = append(i.ResourceArns, lb.LoadBalancerArn)
+		}
+
+		o, err := awsClients.ELBv2.DescribeTags(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range o.TagDescriptions {
+			t := map[string]string{}
+			for _, tag := range d.Tags {
+				t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			tags[aws.StringValue(d.ResourceArn)] = t
+		}
+	}
+
+	for _, lb := range loadBalancers {
+		arn := aws.StringValue(lb.LoadBalancerArn)
+		if tags[arn][key.TagInstallation] != installation {
+			continue
+		}
+
+		info := elbv2Info{
+			Name: aws.StringValue(lb.LoadBalancerName),
+			Tags: tags[arn],
+			Type: aws.StringValue(lb.Type),
+		}
+		if lb.State != nil {
+			info.State = aws.StringValue(lb.State.Code)
+		}
+
+		listeners, err := countELBv2Listeners(lb.LoadBalancerArn, awsClients)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		info.Listeners = listeners
+
+		targetGroups, err := getELBv2TargetGroups(lb.LoadBalancerArn, awsClients)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		info.TargetGroups = targetGroups
+
+		res.LoadBalancers = append(res.LoadBalancers, info)
+	}
+
+	return res, nil
+}
+
+func countELBv2Listeners(loadBalancerArn *string, awsClients clientaws.Clients) (float64, error) {
+	var count float64
+
+	i := &elbv2.DescribeListenersInput{
+		LoadBalancerArn: loadBalancerArn,
+	}
+	for {
+		o, err := awsClients.ELBv2.DescribeListeners(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		count += float64(len(o.Listeners))
+
+		if o.NextMarker == nil {
+			break
+		}
+		i.SetMarker(*o.NextMarker)
+	}
+
+	return count, nil
+}
+
+// getELBv2TargetGroups returns the target groups of the given load balancer
+// with the number of their targets in every health state. States without
+// targets are reported with 0, so that alerts do not have to deal with
+// missing series.
+func getELBv2TargetGroups(loadBalancerArn *string, awsClients clientaws.Clients) ([]elbv2TargetGroupInfo, error) {
+	var targetGroups []*elbv2.TargetGroup
+	{
+		i := &elbv2.DescribeTargetGroupsInput{
+			LoadBalancerArn: loadBalancerArn,
+		}
+		for {
+			o, err := awsClients.ELBv2.DescribeTargetGroups(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			targetGroups = append(targetGroups, o.TargetGroups...)
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
+		}
+	}
+
+	var infos []elbv2TargetGroupInfo
+	for _, tg := range targetGroups {
+		info := elbv2TargetGroupInfo{
+			Name:    aws.StringValue(tg.TargetGroupName),
+			Targets: map[string]float64{},
+		}
+		for _, state := range elbv2.TargetHealthStateEnum_Values() {
+			info.Targets[state] = 0
+		}
+
+		i := &elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: tg.TargetGroupArn,
+		}
+		o, err := awsClients.ELBv2.DescribeTargetHealth(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range o.TargetHealthDescriptions {
+			if d.TargetHealth == nil {
+				continue
+			}
+			info.Targets[aws.StringValue(d.TargetHealth.State)]++
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
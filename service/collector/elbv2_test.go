@@ -0,0 +1,182 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type elbv2Mock struct {
+	elbv2iface.ELBV2API
+
+	describeCalls int
+	loadBalancers []*elbv2.LoadBalancer
+	// listeners, targetGroups and tags are keyed by load balancer ARN.
+	listeners    map[string][]*elbv2.Listener
+	targetGroups map[string][]*elbv2.TargetGroup
+	tags         map[string][]*elbv2.Tag
+	// targetHealth is keyed by target group ARN.
+	targetHealth map[string][]*elbv2.TargetHealthDescription
+}
+
+func (e *elbv2Mock) DescribeLoadBalancers(*elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	e.describeCalls++
+	return &elbv2.DescribeLoadBalancersOutput{LoadBalancers: e.loadBalancers}, nil
+}
+
+func (e *elbv2Mock) DescribeListeners(i *elbv2.DescribeListenersInput) (*elbv2.DescribeListenersOutput, error) {
+	return &elbv2.DescribeListenersOutput{Listeners: e.listeners[*i.LoadBalancerArn]}, nil
+}
+
+func (e *elbv2Mock) DescribeTags(i *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	o := &elbv2.DescribeTagsOutput{}
+	for _, arn := range i.ResourceArns {
+		o.TagDescriptions = append(o.TagDescriptions, &elbv2.TagDescription{
+			ResourceArn: arn,
+			Tags:        e.tags[*arn],
+		})
+	}
+
+	return o, nil
+}
+
+func (e *elbv2Mock) DescribeTargetGroups(i *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	return &elbv2.DescribeTargetGroupsOutput{TargetGroups: e.targetGroups[*i.LoadBalancerArn]}, nil
+}
+
+func (e *elbv2Mock) DescribeTargetHealth(i *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: e.targetHealth[*i.TargetGroupArn]}, nil
+}
+
+func targetHealth(state string) *elbv2.TargetHealthDescription {
+	return &elbv2.TargetHealthDescription{
+		TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+	}
+}
+
+func Test_getELBv2InfoFromAPI(t *testing.T) {
+	awsClients := clientaws.Clients{
+		ELBv2: &elbv2Mock{
+			loadBalancers: []*elbv2.LoadBalancer{
+				{
+					LoadBalancerArn:  aws.String("arn-alb"),
+					LoadBalancerName: aws.String("alb"),
+					State:            &elbv2.LoadBalancerState{Code: aws.String(elbv2.LoadBalancerStateEnumActive)},
+					Type:             aws.String(elbv2.LoadBalancerTypeEnumApplication),
+				},
+				{
+					LoadBalancerArn:  aws.String("arn-other"),
+					LoadBalancerName: aws.String("other"),
+					State:            &elbv2.LoadBalancerState{Code: aws.String(elbv2.LoadBalancerStateEnumActive)},
+					Type:             aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+				},
+			},
+			listeners: map[string][]*elbv2.Listener{
+				"arn-alb": {{}, {}},
+			},
+			tags: map[string][]*elbv2.Tag{
+				"arn-alb": {
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+					{Key: aws.String(tagCluster), Value: aws.String("al9qy")},
+				},
+				// Load balancers of other installations are ignored.
+				"arn-other": {
+					{Key: aws.String(key.TagInstallation), Value: aws.String("other")},
+				},
+			},
+			targetGroups: map[string][]*elbv2.TargetGroup{
+				"arn-alb": {
+					{TargetGroupArn: aws.String("arn-tg"), TargetGroupName: aws.String("tg")},
+				},
+			},
+			targetHealth: map[string][]*elbv2.TargetHealthDescription{
+				"arn-tg": {
+					targetHealth(elbv2.TargetHealthStateEnumHealthy),
+					targetHealth(elbv2.TargetHealthStateEnumHealthy),
+					targetHealth(elbv2.TargetHealthStateEnumDraining),
+					targetHealth(elbv2.TargetHealthStateEnumUnhealthy),
+				},
+			},
+		},
+	}
+
+	res, err := getELBv2InfoFromAPI("test", awsClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &elbv2InfoResponse{
+		LoadBalancers: []elbv2Info{
+			{
+				Listeners: 2,
+				Name:      "alb",
+				State:     elbv2.LoadBalancerStateEnumActive,
+				Tags: map[string]string{
+					key.TagInstallation: "test",
+					tagCluster:          "al9qy",
+				},
+				TargetGroups: []elbv2TargetGroupInfo{
+					{
+						Name: "tg",
+						Targets: map[string]float64{
+							elbv2.TargetHealthStateEnumDraining:    1,
+							elbv2.TargetHealthStateEnumHealthy:     2,
+							elbv2.TargetHealthStateEnumInitial:     0,
+							elbv2.TargetHealthStateEnumUnavailable: 0,
+							elbv2.TargetHealthStateEnumUnhealthy:   1,
+							elbv2.TargetHealthStateEnumUnused:      0,
+						},
+					},
+				},
+				Type: elbv2.LoadBalancerTypeEnumApplication,
+			},
+		},
+	}
+
+	if !cmp.Equal(res, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, res))
+	}
+}
+
+func Test_ELBv2_collectForAccount_cache(t *testing.T) {
+	e, err := NewELBv2(ELBv2Config{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &elbv2Mock{}
+	awsClients := clientaws.Clients{
+		ELBv2:  mock,
+		Region: "eu-central-1",
+	}
+
+	// Regions without load balancers are cached as well, so the second
+	// collection does not list the load balancers again.
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 10)
+		err := e.collectForAccount(ch, awsClients, "000000000000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ch) != 0 {
+			t.Fatalf("expected no metrics, got %d", len(ch))
+		}
+	}
+
+	if mock.describeCalls != 1 {
+		t.Fatalf("expected 1 DescribeLoadBalancers call, got %d", mock.describeCalls)
+	}
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// accountCollectorFunc adapts the collection of a single account to the
+// prometheus.Collector interface, so that the collected metrics can be
+// gathered and rendered in the text exposition format.
+type accountCollectorFunc struct {
+	t *testing.T
+
+	collect  func(ch chan<- prometheus.Metric) error
+	describe func(ch chan<- *prometheus.Desc) error
+}
+
+// Collect and Describe are called on the goroutines of the registry, so errors
+// are only reported with t.Error, which is safe to call from any goroutine.
+func (a accountCollectorFunc) Collect(ch chan<- prometheus.Metric) {
+	err := a.collect(ch)
+	if err != nil {
+		a.t.Error(err)
+	}
+}
+
+func (a accountCollectorFunc) Describe(ch chan<- *prometheus.Desc) {
+	err := a.describe(ch)
+	if err != nil {
+		a.t.Error(err)
+	}
+}
+
+// gatherText renders the metrics of the given collector in the text
+// exposition format, sorted by metric name and labels.
+func gatherText(t *testing.T, c prometheus.Collector) []byte {
+	registry := prometheus.NewPedanticRegistry()
+
+	err := registry.Register(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Errors of the collector are reported on the registry's goroutines.
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	for _, f := range families {
+		_, err := expfmt.MetricFamilyToText(&buf, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// compareGolden compares the given output with the golden file of the given
+// name in testdata. Golden files are rewritten when running the tests with
+// -update.
+func compareGolden(t *testing.T, name string, output []byte) {
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		err := ioutil.WriteFile(path, output, 0644) // nolint: gosec
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(output, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(output)))
+	}
+}
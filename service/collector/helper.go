@@ -3,34 +3,158 @@ package collector
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
-	"github.com/giantswarm/k8sclient/v5/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
 	"github.com/giantswarm/aws-collector/service/internal/accountid"
+	"github.com/giantswarm/aws-collector/service/internal/clientpool"
 	"github.com/giantswarm/aws-collector/service/internal/credential"
 )
 
+const (
+	// subsystemCollector will become the second part of the metric name of the
+	// metrics about the collection itself, right after namespace.
+	subsystemCollector = "collector"
+)
+
+var (
+	collectorAccountUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemCollector, "account_up"),
+		"Gauge indicating whether a collector could collect the metrics of an AWS account. 1 = success, 0 = failure",
+		[]string{
+			labelAccountID,
+			labelCollector,
+			labelRegion,
+		},
+		nil,
+	)
+	collectorAccountLastSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemCollector, "last_success_timestamp_seconds"),
+		"Unix timestamp of the last time a collector successfully collected the metrics of an AWS account.",
+		[]string{
+			labelAccountID,
+			labelCollector,
+			labelRegion,
+		},
+		nil,
+	)
+	collectorAccountRefreshDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemCollector, "refresh_duration_seconds"),
+		"Duration of the last time a collector collected the metrics of an AWS account.",
+		[]string{
+			labelAccountID,
+			labelCollector,
+			labelRegion,
+		},
+		nil,
+	)
+	collectorClusterCredentialUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemCollector, "cluster_credential_up"),
+		"Gauge indicating whether the AWS account of a cluster could be found from its credential. 1 = success, 0 = failure",
+		[]string{
+			labelCluster,
+		},
+		nil,
+	)
+)
+
 type helperConfig struct {
-	Clients k8sclient.Interface
-	Logger  micrologger.Logger
+	K8sClient client.Reader
+	Logger    micrologger.Logger
 
 	AWSConfig clientaws.Config
+	// DiscoveryMaxAge is the age after which the discovery snapshot is not
+	// served anymore, e.g. because it failed to be refreshed for too long.
+	DiscoveryMaxAge time.Duration
+	// InstallationName is used to find the regions containing resources of the
+	// installation when RegionDiscoveryEnabled is set.
+	InstallationName string
+	// RegionDiscoveryEnabled adds every region enabled in an account which
+	// contains resources of the installation to Regions.
+	RegionDiscoveryEnabled bool
+	// RegionDiscoveryTTL is the time discovered regions are cached for.
+	RegionDiscoveryTTL time.Duration
+	// Regions are the AWS regions metrics are collected in, in addition to the
+	// region of AWSConfig.
+	Regions []string
 }
 
 type helper struct {
-	clients k8sclient.Interface
-	logger  micrologger.Logger
+	k8sClient client.Reader
+	logger    micrologger.Logger
+
+	accountErrors    *prometheus.CounterVec
+	clientPool       *clientpool.Pool
+	discovery        *discovery
+	discoveryMaxAge  time.Duration
+	installationName string
+	lastSuccess      map[accountCollector]time.Time
+	mutex            sync.Mutex
+	// now is only meant to be replaced in tests.
+	now         func() time.Time
+	region      string
+	regionCache *regionCache
+	regions     []string
+	// refreshErr is the error of the latest Refresh. It is returned by
+	// Discovery instead of computing the snapshot again, so that a failed
+	// Refresh is not repeated by every collector of the same scrape.
+	refreshErr error
+}
+
+// accountCollector identifies the collection of one collector in one region
+// of one AWS account.
+type accountCollector struct {
+	AccountID string
+	Collector string
+	Region    string
+}
 
-	awsConfig clientaws.Config
+// discovery is a snapshot of the clusters and AWS accounts metrics are
+// collected for, together with the control planes and node pools of the
+// clusters. It is computed once per scrape and shared by all collectors,
+// so that they all see a consistent set of accounts and the Kubernetes API is
+// only asked once.
+type discovery struct {
+	Accounts           *awsAccounts
+	Clusters           *infrastructurev1alpha3.AWSClusterList
+	ControlPlanes      *infrastructurev1alpha3.AWSControlPlaneList
+	MachineDeployments *infrastructurev1alpha3.AWSMachineDeploymentList
+	// Time is when the snapshot was computed.
+	Time time.Time
+}
+
+// awsAccounts holds the AWS clients of every region of every account metrics
+// are collected for, keyed by account ID. Accounts for which no working
+// clients could be set up are tracked with their error instead. The accounts
+// of the clusters are tracked by cluster ID, and clusters whose account can
+// not be found from their credential are tracked with their error.
+type awsAccounts struct {
+	Clients         map[string][]clientaws.Clients
+	ClusterAccounts map[string]string
+	ClusterErrors   map[string]error
+	Errors          map[string]accountError
+}
+
+// accountError is the reason no working clients could be set up for an
+// account, together with the regions its metrics would have been collected
+// in, so that its failure is reported for the same regions as its successes.
+type accountError struct {
+	Err     error
+	Regions []string
 }
 
 func newHelper(config helperConfig) (*helper, error) {
-	if config.Clients == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.Clients must not be empty", config)
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
@@ -40,145 +164,506 @@ func newHelper(config helperConfig) (*helper, error) {
 	if config.AWSConfig == emptyAWSConfig {
 		return nil, microerror.Maskf(invalidConfigError, "%T.AWSConfig must not be empty", config)
 	}
+	if config.DiscoveryMaxAge <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DiscoveryMaxAge must be greater than 0", config)
+	}
 
-	h := &helper{
-		clients: config.Clients,
-		logger:  config.Logger,
+	if config.RegionDiscoveryEnabled && config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty when region discovery is enabled", config)
+	}
+	if config.RegionDiscoveryEnabled && config.RegionDiscoveryTTL <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RegionDiscoveryTTL must be greater than 0 when region discovery is enabled", config)
+	}
+
+	var err error
 
-		awsConfig: config.AWSConfig,
+	var clientPool *clientpool.Pool
+	{
+		c := clientpool.Config{
+			Logger: config.Logger,
+
+			AWSConfig: config.AWSConfig,
+		}
+
+		clientPool, err = clientpool.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The region of the control plane account is always collected, further
+	// regions are added in the configured order.
+	regions := []string{config.AWSConfig.Region}
+	for _, r := range config.Regions {
+		if r == "" || containsString(regions, r) {
+			continue
+		}
+		regions = append(regions, r)
+	}
+
+	var rc *regionCache
+	if config.RegionDiscoveryEnabled {
+		rc = newRegionCache(config.RegionDiscoveryTTL)
+	}
+
+	h := &helper{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		accountErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystemCollector,
+				Name:      "account_errors_total",
+				Help:      "Number of failed attempts of a collector to collect the metrics of an AWS account.",
+			},
+			[]string{
+				labelAccountID,
+				labelCollector,
+				labelRegion,
+			},
+		),
+		clientPool:       clientPool,
+		discovery:        nil,
+		discoveryMaxAge:  config.DiscoveryMaxAge,
+		installationName: config.InstallationName,
+		lastSuccess:      make(map[accountCollector]time.Time),
+		mutex:            sync.Mutex{},
+		now:              time.Now,
+		region:           config.AWSConfig.Region,
+		regionCache:      rc,
+		regions:          regions,
 	}
 
 	return h, nil
 }
 
-// GetARNs list all unique aws IAM ARN from credential secret.
-func (h *helper) GetARNs(ctx context.Context, clusterList *infrastructurev1alpha3.AWSClusterList) ([]string, error) {
-	var arns []string
+// clusterCredentials are the role ARNs found in the credential secrets of the
+// clusters.
+type clusterCredentials struct {
+	// ARNs are the unique role ARNs of all clusters, including the default one.
+	ARNs []string
+	// ClusterARNs maps cluster IDs to the role ARN of their account.
+	ClusterARNs map[string]string
+	// Errors maps the IDs of clusters whose role ARN can not be found to the
+	// reason.
+	Errors map[string]error
+}
+
+// GetARNs list all unique aws IAM ARN from credential secret. Clusters whose
+// credential secret or ARN is missing are returned as errors keyed by cluster
+// ID, so that the accounts of all other clusters can still be collected.
+func (h *helper) GetARNs(ctx context.Context, clusterList *infrastructurev1alpha3.AWSClusterList) (*clusterCredentials, error) {
+	credentials := &clusterCredentials{
+		ClusterARNs: make(map[string]string),
+		Errors:      make(map[string]error),
+	}
 
 	// Get unique ARNs.
 	arnsMap := make(map[string]bool)
+	var defaultClusters []string
 	for _, clusterCR := range clusterList.Items {
-		arn, err := credential.GetARN(ctx, h.clients.K8sClient(), clusterCR)
+		arn, err := credential.GetARN(ctx, h.k8sClient, clusterCR)
 		// Collect as many ARNs as possible in order to provide most metrics.
-		// Ignore old cluster which do not have credential.
+		// Old clusters which do not have credential use the default one.
 		if credential.IsCredentialNameEmptyError(err) {
+			defaultClusters = append(defaultClusters, key.ClusterID(clusterCR))
 			continue
 		} else if credential.IsCredentialNamespaceEmptyError(err) {
+			defaultClusters = append(defaultClusters, key.ClusterID(clusterCR))
+			continue
+		} else if isCredentialMissing(err) {
+			h.logger.Log("level", "error", "message", fmt.Sprintf("failed finding account of cluster %s", key.ClusterID(clusterCR)), "stack", fmt.Sprintf("%#v", err))
+			credentials.Errors[key.ClusterID(clusterCR)] = err
 			continue
 		} else if err != nil {
 			return nil, microerror.Mask(err)
 		}
 
 		arnsMap[arn] = true
+		credentials.ClusterARNs[key.ClusterID(clusterCR)] = arn
 	}
 
 	// Ensure we check the default guest account for old cluster not having credential.
-	arn, err := credential.GetDefaultARN(ctx, h.clients.K8sClient())
-	if err != nil {
+	arn, err := credential.GetDefaultARN(ctx, h.k8sClient)
+	if isCredentialMissing(err) {
+		h.logger.Log("level", "debug", "message", "failed finding default account", "stack", fmt.Sprintf("%#v", err))
+		for _, id := range defaultClusters {
+			credentials.Errors[id] = err
+		}
+	} else if err != nil {
 		return nil, microerror.Mask(err)
+	} else {
+		arnsMap[arn] = true
+		for _, id := range defaultClusters {
+			credentials.ClusterARNs[id] = arn
+		}
 	}
-	arnsMap[arn] = true
 
 	for arn := range arnsMap {
-		arns = append(arns, arn)
+		credentials.ARNs = append(credentials.ARNs, arn)
 	}
 
-	return arns, nil
+	return credentials, nil
 }
 
-// GetAWSClients return a list of aws clients for every guest cluster account plus
-// the host cluster account.
-func (h *helper) GetAWSClients(ctx context.Context, clusterList *infrastructurev1alpha3.AWSClusterList) ([]clientaws.Clients, error) {
-	var (
-		clients    []clientaws.Clients
-		clientsMap = make(map[string]clientaws.Clients)
-	)
-
-	arns, err := h.GetARNs(ctx, clusterList)
+// GetAWSClients return the aws clients for every configured region of every
+// guest cluster account plus the host cluster account. Guest cluster accounts for which no working
+// clients can be set up, e.g. because the role ARN can not be assumed, are
+// returned as errors so that all other accounts can still be collected.
+func (h *helper) GetAWSClients(ctx context.Context, clusterList *infrastructurev1alpha3.AWSClusterList) (*awsAccounts, error) {
+	credentials, err := h.GetARNs(ctx, clusterList)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+	arns := credentials.ARNs
+
+	accounts := &awsAccounts{
+		Clients:         make(map[string][]clientaws.Clients),
+		ClusterAccounts: make(map[string]string),
+		ClusterErrors:   credentials.Errors,
+		Errors:          make(map[string]accountError),
+	}
+	arnAccounts := make(map[string]string)
+
+	// Evict the clients of accounts no longer used by any cluster.
+	h.clientPool.Retain(arns)
+
+	// Regions of accounts not cached yet are discovered concurrently instead
+	// of one account after another below.
+	h.discoverAccountRegions(append([]string{""}, arns...))
+
+	// Control plane account. The tenant cluster roles are assumed using the
+	// control plane credentials, so without them nothing can be collected.
+	{
+		awsClients, err := h.regionalClients("")
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		accountID, err := h.clientPool.AccountID("")
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		accounts.Clients[accountID] = awsClients
+	}
+
+	// Tenant cluster accounts.
+	for _, arn := range arns {
+		awsClients, err := h.regionalClients(arn)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 
-	// addClientFunc add awsClients to clients using account id as key to guaranatee uniqueness.
-	addClientFunc := func(awsClients clientaws.Clients, clients map[string]clientaws.Clients) error {
-		accountID, err := h.AWSAccountID(awsClients)
+		accountID, err := h.clientPool.AccountID(arn)
 		if err != nil {
-			return microerror.Mask(err)
+			// The role could not be assumed, so we fall back to the account ID
+			// contained in the role ARN in order to report the failure.
+			id, arnErr := accountid.FromARN(arn)
+			if arnErr != nil {
+				id = arn
+			}
+			arnAccounts[arn] = id
+			if _, ok := accounts.Clients[id]; !ok {
+				var regions []string
+				for _, c := range awsClients {
+					regions = append(regions, c.Region)
+				}
+
+				accounts.Errors[id] = accountError{
+					Err:     err,
+					Regions: regions,
+				}
+			}
+
+			continue
 		}
 
-		_, ok := clients[accountID]
+		arnAccounts[arn] = accountID
+
+		// Use account id as key to guarantee uniqueness.
+		_, ok := accounts.Clients[accountID]
 		if !ok {
-			clients[accountID] = awsClients
+			accounts.Clients[accountID] = awsClients
+			delete(accounts.Errors, accountID)
 		}
+	}
 
-		return nil
+	for clusterID, arn := range credentials.ClusterARNs {
+		accounts.ClusterAccounts[clusterID] = arnAccounts[arn]
+	}
+
+	for accountID := range accounts.Clients {
+		h.logger.Log("level", "debug", "message", fmt.Sprintf("collecting metrics in account: %s", accountID))
 	}
 
-	// Control plane account.
-	awsClients, err := clientaws.NewClients(h.awsConfig)
+	return accounts, nil
+}
+
+// CollectForAccounts executes collectFunc concurrently for every region of
+// every AWS account metrics are collected for. Failures of single accounts do
+// not fail the collection as a whole. They are logged and reported by the
+// account_up and account_errors_total metrics instead, so that one
+// misconfigured account does not prevent the metrics of all other accounts
+// from being emitted.
+func (h *helper) CollectForAccounts(ch chan<- prometheus.Metric, name string, collectFunc func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error) error {
+	d, err := h.Discovery(context.Background())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	accounts := d.Accounts
+
+	for accountID, e := range accounts.Errors {
+		for _, region := range e.Regions {
+			h.accountFailed(ch, name, accountID, region, e.Err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for id, regionalClients := range accounts.Clients {
+		for _, item := range regionalClients {
+			accountID := id
+			awsClients := item
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				start := h.now()
+				err := collectFunc(ch, awsClients, accountID)
+
+				ch <- prometheus.MustNewConstMetric(
+					collectorAccountRefreshDurationDesc,
+					prometheus.GaugeValue,
+					h.now().Sub(start).Seconds(),
+					accountID,
+					name,
+					awsClients.Region,
+				)
+
+				if err != nil {
+					h.accountFailed(ch, name, accountID, awsClients.Region, err)
+					return
+				}
+
+				h.accountSucceeded(ch, name, accountID, awsClients.Region)
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// Discovery returns the latest discovery snapshot. The snapshot is only
+// computed here if Refresh was not called yet, or if the latest snapshot is
+// older than the maximum age. In the latter case collectors fail instead of
+// working with clusters and accounts which are long gone. If the latest
+// Refresh failed its error is returned until Refresh is called again.
+func (h *helper) Discovery(ctx context.Context) (*discovery, error) {
+	h.mutex.Lock()
+	d := h.discovery
+	refreshErr := h.refreshErr
+	h.mutex.Unlock()
+
+	if d != nil && h.now().Sub(d.Time) <= h.discoveryMaxAge {
+		return d, nil
+	}
+	if refreshErr != nil {
+		return nil, microerror.Mask(refreshErr)
+	}
+
+	d, err := h.Refresh(ctx)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	err = addClientFunc(awsClients, clientsMap)
+
+	return d, nil
+}
+
+// Refresh computes a new discovery snapshot and makes it the one returned by
+// Discovery. On failure the previous snapshot is kept until it exceeds the
+// maximum age.
+func (h *helper) Refresh(ctx context.Context) (*discovery, error) {
+	d, err := h.computeDiscovery(ctx)
+
+	h.mutex.Lock()
+	if err == nil {
+		h.discovery = d
+	}
+	h.refreshErr = err
+	h.mutex.Unlock()
+
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	// Tenant cluster accounts.
-	for _, arn := range arns {
-		awsConfig := h.awsConfig
-		awsConfig.RoleARN = arn
+	return d, nil
+}
 
-		awsClients, err := clientaws.NewClients(awsConfig)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		err = addClientFunc(awsClients, clientsMap)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+func (h *helper) computeDiscovery(ctx context.Context) (*discovery, error) {
+	now := h.now()
+
+	reconciledClusters, err := h.ListReconciledClusters(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	// Convert map to slice.
-	for accountID, client := range clientsMap {
-		clients = append(clients, client)
-		h.logger.Log("level", "debug", "message", fmt.Sprintf("collecting metrics in account: %s", accountID))
+	accounts, err := h.GetAWSClients(ctx, reconciledClusters)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	return clients, nil
+	controlPlanes := &infrastructurev1alpha3.AWSControlPlaneList{}
+	err = h.k8sClient.List(ctx, controlPlanes)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	machineDeployments := &infrastructurev1alpha3.AWSMachineDeploymentList{}
+	err = h.k8sClient.List(ctx, machineDeployments)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	d := &discovery{
+		Accounts:           accounts,
+		Clusters:           reconciledClusters,
+		ControlPlanes:      controlPlanes,
+		MachineDeployments: machineDeployments,
+		Time:               now,
+	}
+
+	return d, nil
 }
 
-// AWSAccountID return the AWS account ID.
-func (h *helper) AWSAccountID(awsClients clientaws.Clients) (string, error) {
-	var err error
+// Collect emits the metrics the helper tracks across collections, and whether
+// the accounts of the clusters of the latest snapshot could be found.
+func (h *helper) Collect(ch chan<- prometheus.Metric) {
+	h.accountErrors.Collect(ch)
 
-	var accountIDService *accountid.AccountID
-	{
-		c := accountid.Config{
-			Logger: h.logger,
-			STS:    awsClients.STS,
+	h.mutex.Lock()
+	d := h.discovery
+	h.mutex.Unlock()
+
+	if d == nil {
+		return
+	}
+
+	for _, cluster := range d.Clusters.Items {
+		up := 1.0
+		if _, ok := d.Accounts.ClusterErrors[key.ClusterID(cluster)]; ok {
+			up = 0
 		}
 
-		accountIDService, err = accountid.New(c)
+		ch <- prometheus.MustNewConstMetric(
+			collectorClusterCredentialUpDesc,
+			prometheus.GaugeValue,
+			up,
+			key.ClusterID(cluster),
+		)
+	}
+}
+
+// Describe emits the description of the metrics about the collection itself.
+func (h *helper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collectorAccountUpDesc
+	ch <- collectorAccountLastSuccessDesc
+	ch <- collectorAccountRefreshDurationDesc
+	ch <- collectorClusterCredentialUpDesc
+	h.accountErrors.Describe(ch)
+}
+
+func (h *helper) accountFailed(ch chan<- prometheus.Metric, name string, accountID string, region string, err error) {
+	h.logger.Log("level", "error", "message", fmt.Sprintf("failed collecting %s metrics in account %s and region %s", name, accountID, region), "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+
+	h.accountErrors.WithLabelValues(accountID, name, region).Inc()
+
+	ch <- prometheus.MustNewConstMetric(
+		collectorAccountUpDesc,
+		prometheus.GaugeValue,
+		0,
+		accountID,
+		name,
+		region,
+	)
+
+	h.mutex.Lock()
+	lastSuccess, ok := h.lastSuccess[accountCollector{AccountID: accountID, Collector: name, Region: region}]
+	h.mutex.Unlock()
+
+	if ok {
+		ch <- prometheus.MustNewConstMetric(
+			collectorAccountLastSuccessDesc,
+			prometheus.GaugeValue,
+			float64(lastSuccess.Unix()),
+			accountID,
+			name,
+			region,
+		)
+	}
+}
+
+func (h *helper) accountSucceeded(ch chan<- prometheus.Metric, name string, accountID string, region string) {
+	// Make sure the error counter is exposed with a value of 0 for healthy
+	// accounts as well.
+	h.accountErrors.WithLabelValues(accountID, name, region)
+
+	ch <- prometheus.MustNewConstMetric(
+		collectorAccountUpDesc,
+		prometheus.GaugeValue,
+		1,
+		accountID,
+		name,
+		region,
+	)
+
+	now := h.now()
+
+	h.mutex.Lock()
+	h.lastSuccess[accountCollector{AccountID: accountID, Collector: name, Region: region}] = now
+	h.mutex.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(
+		collectorAccountLastSuccessDesc,
+		prometheus.GaugeValue,
+		float64(now.Unix()),
+		accountID,
+		name,
+		region,
+	)
+}
+
+// regionalClients returns the clients of every region metrics are collected in
+// for the given role ARN.
+func (h *helper) regionalClients(arn string) ([]clientaws.Clients, error) {
+	var clients []clientaws.Clients
+
+	for _, region := range h.accountRegions(arn) {
+		awsClients, err := h.clientPool.Get(arn, region)
 		if err != nil {
-			return "", microerror.Mask(err)
+			return nil, microerror.Mask(err)
 		}
-	}
 
-	accountID, err := accountIDService.Lookup()
-	if err != nil {
-		return "", microerror.Mask(err)
+		clients = append(clients, awsClients)
 	}
 
-	return accountID, nil
+	return clients, nil
 }
 
-// ListReconciledClusters provides a list of clusters
-func (h *helper) ListReconciledClusters() (*infrastructurev1alpha3.AWSClusterList, error) {
-	ctx := context.Background()
+// isCredentialMissing returns whether err means that the credential secret of a
+// cluster or its ARN does not exist.
+func isCredentialMissing(err error) bool {
+	return apierrors.IsNotFound(microerror.Cause(err)) || credential.IsArnNotFoundError(err)
+}
 
+// ListReconciledClusters provides a list of clusters
+func (h *helper) ListReconciledClusters(ctx context.Context) (*infrastructurev1alpha3.AWSClusterList, error) {
 	clusters := &infrastructurev1alpha3.AWSClusterList{}
-	err := h.clients.CtrlClient().List(
+	err := h.k8sClient.List(
 		ctx,
 		clusters,
 	)
@@ -187,3 +672,13 @@ func (h *helper) ListReconciledClusters() (*infrastructurev1alpha3.AWSClusterLis
 	}
 	return clusters, err
 }
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
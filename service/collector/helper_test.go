@@ -0,0 +1,537 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/internal/clientpool"
+	"github.com/giantswarm/aws-collector/service/internal/credential"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+
+	err := corev1.AddToScheme(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = infrastructurev1alpha3.AddToScheme(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func newTestHelper(t *testing.T, k8sClient client.Reader) *helper {
+	c := helperConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+
+		AWSConfig: clientaws.Config{
+			AccessKeyID:     "id",
+			AccessKeySecret: "secret",
+			Region:          "eu-central-1",
+		},
+		DiscoveryMaxAge: time.Minute,
+	}
+
+	h, err := newHelper(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func newTestCredential(name string, arn string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: credential.DefaultNamespace,
+		},
+		Data: map[string][]byte{
+			credential.AWSOperatorArnKey: []byte(arn),
+		},
+	}
+}
+
+func newTestCluster(id string, credentialName string) *infrastructurev1alpha3.AWSCluster {
+	cr := &infrastructurev1alpha3.AWSCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      id,
+			Namespace: "default",
+			Labels: map[string]string{
+				label.Cluster: id,
+			},
+		},
+	}
+	cr.Spec.Provider.CredentialSecret.Name = credentialName
+	cr.Spec.Provider.CredentialSecret.Namespace = credential.DefaultNamespace
+
+	return cr
+}
+
+// Test_helper_Discovery_NewAccounts ensures that clusters and accounts created
+// after the helper got set up are discovered without restarting it.
+func Test_helper_Discovery_NewAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	defaultARN := "arn:aws:iam::000000000000:role/GiantSwarmAWSOperator"
+	firstARN := "arn:aws:iam::111111111111:role/GiantSwarmAWSOperator"
+	secondARN := "arn:aws:iam::222222222222:role/GiantSwarmAWSOperator"
+
+	k8sClient := fake.NewFakeClientWithScheme(
+		newTestScheme(t),
+		newTestCredential(credential.DefaultName, defaultARN),
+		newTestCredential("credential-first", firstARN),
+		newTestCluster("al9qy", "credential-first"),
+	)
+
+	h := newTestHelper(t, k8sClient)
+
+	{
+		clusters, err := h.ListReconciledClusters(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(clusters.Items) != 1 {
+			t.Fatalf("expected 1 cluster, got %d", len(clusters.Items))
+		}
+
+		credentials, err := h.GetARNs(ctx, clusters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arns := credentials.ARNs
+		sort.Strings(arns)
+
+		expected := []string{defaultARN, firstARN}
+		if !cmp.Equal(arns, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, arns))
+		}
+	}
+
+	// A new cluster using another account shows up.
+	{
+		err := k8sClient.Create(ctx, newTestCredential("credential-second", secondARN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = k8sClient.Create(ctx, newTestCluster("x7k2e", "credential-second"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		clusters, err := h.ListReconciledClusters(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(clusters.Items) != 2 {
+			t.Fatalf("expected 2 clusters, got %d", len(clusters.Items))
+		}
+
+		credentials, err := h.GetARNs(ctx, clusters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arns := credentials.ARNs
+		sort.Strings(arns)
+
+		expected := []string{defaultARN, firstARN, secondARN}
+		if !cmp.Equal(arns, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, arns))
+		}
+	}
+
+	// The deleted cluster's account is no longer discovered.
+	{
+		err := k8sClient.Delete(ctx, newTestCluster("al9qy", "credential-first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		clusters, err := h.ListReconciledClusters(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		credentials, err := h.GetARNs(ctx, clusters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arns := credentials.ARNs
+		sort.Strings(arns)
+
+		expected := []string{defaultARN, secondARN}
+		if !cmp.Equal(arns, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, arns))
+		}
+	}
+}
+
+// Test_helper_GetARNs_MissingCredential ensures that clusters whose credential
+// secret or ARN is missing are reported without failing the discovery of the
+// accounts of all other clusters.
+func Test_helper_GetARNs_MissingCredential(t *testing.T) {
+	ctx := context.Background()
+
+	defaultARN := "arn:aws:iam::000000000000:role/GiantSwarmAWSOperator"
+	firstARN := "arn:aws:iam::111111111111:role/GiantSwarmAWSOperator"
+
+	noARN := newTestCredential("credential-no-arn", "")
+	noARN.Data = nil
+
+	k8sClient := fake.NewFakeClientWithScheme(
+		newTestScheme(t),
+		newTestCredential(credential.DefaultName, defaultARN),
+		newTestCredential("credential-first", firstARN),
+		noARN,
+		newTestCluster("al9qy", "credential-first"),
+		newTestCluster("x7k2e", "credential-missing"),
+		newTestCluster("p3m8w", "credential-no-arn"),
+	)
+
+	h := newTestHelper(t, k8sClient)
+
+	clusters, err := h.ListReconciledClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, err := h.GetARNs(ctx, clusters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arns := credentials.ARNs
+	sort.Strings(arns)
+
+	expected := []string{defaultARN, firstARN}
+	if !cmp.Equal(arns, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, arns))
+	}
+
+	var failed []string
+	for id := range credentials.Errors {
+		failed = append(failed, id)
+	}
+	sort.Strings(failed)
+
+	expectedFailed := []string{"p3m8w", "x7k2e"}
+	if !cmp.Equal(failed, expectedFailed) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedFailed, failed))
+	}
+
+	expectedClusterARNs := map[string]string{"al9qy": firstARN}
+	if !cmp.Equal(credentials.ClusterARNs, expectedClusterARNs) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedClusterARNs, credentials.ClusterARNs))
+	}
+}
+
+// failingReader fails to list while err is set, e.g. because the Kubernetes
+// API is unavailable. It counts the attempts to list.
+type failingReader struct {
+	client.Reader
+
+	err   error
+	lists int
+}
+
+func (f *failingReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	f.lists++
+	if f.err != nil {
+		return f.err
+	}
+
+	return f.Reader.List(ctx, list, opts...)
+}
+
+// Test_helper_Discovery_Refresh ensures that all collectors share the same
+// snapshot until it is refreshed, and that a snapshot which can not be
+// refreshed is only served up to its maximum age.
+func Test_helper_Discovery_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	k8sClient := fake.NewFakeClientWithScheme(
+		newTestScheme(t),
+		newTestCredential(credential.DefaultName, "arn:aws:iam::000000000000:role/GiantSwarmAWSOperator"),
+		newTestCluster("al9qy", credential.DefaultName),
+	)
+	reader := &failingReader{Reader: k8sClient}
+
+	h := newTestHelper(t, reader)
+	h.clientPool = newTestClientPool(t, clientaws.Config{Region: "eu-central-1"}, func(region string) ec2iface.EC2API {
+		return nil
+	})
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	first, err := h.Discovery(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Clusters.Items) != 1 {
+		t.Fatalf("expected 1 cluster, got %d", len(first.Clusters.Items))
+	}
+	if first.Accounts.ClusterAccounts["al9qy"] != "000000000000" {
+		t.Fatalf("expected cluster account 000000000000, got %#q", first.Accounts.ClusterAccounts["al9qy"])
+	}
+
+	// New clusters only show up once the snapshot is refreshed.
+	err = k8sClient.Create(ctx, newTestCluster("x7k2e", credential.DefaultName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		d, err := h.Discovery(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != first {
+			t.Fatalf("expected the snapshot to be shared")
+		}
+	}
+
+	second, err := h.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Clusters.Items) != 2 {
+		t.Fatalf("expected 2 clusters, got %d", len(second.Clusters.Items))
+	}
+
+	// Failed refreshes keep the previous snapshot up to its maximum age.
+	reader.err = fmt.Errorf("unavailable")
+
+	_, err = h.Refresh(ctx)
+	if err == nil {
+		t.Fatal("expected refresh to fail")
+	}
+
+	now = now.Add(time.Minute)
+	{
+		d, err := h.Discovery(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != second {
+			t.Fatalf("expected the previous snapshot to be served")
+		}
+	}
+
+	now = now.Add(time.Second)
+	_, err = h.Discovery(ctx)
+	if err == nil {
+		t.Fatal("expected the expired snapshot not to be served")
+	}
+
+	// The failed refresh is not repeated by every collector.
+	{
+		lists := reader.lists
+		_, err = h.Discovery(ctx)
+		if err == nil {
+			t.Fatal("expected the expired snapshot not to be served")
+		}
+		if reader.lists != lists {
+			t.Fatalf("expected the failed refresh not to be repeated")
+		}
+	}
+
+	// The snapshot is computed again once the Kubernetes API is available.
+	reader.err = nil
+	{
+		_, err := h.Refresh(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := h.Discovery(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.Time.Equal(now) {
+			t.Fatalf("expected snapshot of %s, got %s", now, d.Time)
+		}
+	}
+}
+
+type stsCallerMock struct {
+	stsiface.STSAPI
+
+	accountID string
+}
+
+func (s *stsCallerMock) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	o := &sts.GetCallerIdentityOutput{
+		Arn: aws.String(fmt.Sprintf("arn:aws:sts::%s:assumed-role/GiantSwarmAWSOperator/session", s.accountID)),
+	}
+
+	return o, nil
+}
+
+// newTestClientPool returns a client pool creating the EC2 clients returned by
+// newEC2 and stubbed STS clients in the requested region. The control plane
+// account is 000000000000, tenant cluster accounts are taken from their role
+// ARN.
+func newTestClientPool(t *testing.T, awsConfig clientaws.Config, newEC2 func(region string) ec2iface.EC2API) *clientpool.Pool {
+	c := clientpool.Config{
+		Logger: microloggertest.New(),
+
+		AWSConfig: awsConfig,
+		NewClients: func(config clientaws.Config) (clientaws.Clients, error) {
+			accountID := "000000000000"
+			if config.RoleARN != "" {
+				accountID = strings.Split(config.RoleARN, ":")[4]
+			}
+
+			awsClients := clientaws.Clients{
+				EC2:    newEC2(config.Region),
+				Region: config.Region,
+				STS:    &stsCallerMock{accountID: accountID},
+			}
+
+			return awsClients, nil
+		},
+	}
+
+	p, err := clientpool.New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func readGauge(t *testing.T, m prometheus.Metric) float64 {
+	var pb dto.Metric
+
+	err := m.Write(&pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pb.Gauge == nil {
+		t.Fatalf("expected gauge, got %s", pb.String())
+	}
+
+	return pb.Gauge.GetValue()
+}
+
+// Test_helper_CollectForAccounts_FailingAccount ensures that a failing
+// account does not prevent the metrics of other accounts from being
+// collected, and that failures are reported in the regions of the failing
+// account.
+func Test_helper_CollectForAccounts_FailingAccount(t *testing.T) {
+	h := newTestHelper(t, fake.NewFakeClientWithScheme(newTestScheme(t)))
+	h.discovery = &discovery{
+		Accounts: &awsAccounts{
+			Clients: map[string][]clientaws.Clients{
+				"111111111111": {{Region: "eu-central-1"}},
+				"333333333333": {{Region: "eu-central-1"}},
+			},
+			Errors: map[string]accountError{
+				// The role of this account can not be assumed. It has
+				// resources in a discovered region only.
+				"222222222222": {
+					Err:     fmt.Errorf("access denied"),
+					Regions: []string{"us-east-1"},
+				},
+			},
+		},
+		Clusters: &infrastructurev1alpha3.AWSClusterList{},
+		Time:     h.now(),
+	}
+
+	testDesc := prometheus.NewDesc("test", "Test metric.", []string{labelAccountID}, nil)
+
+	collectFunc := func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		if accountID == "333333333333" {
+			return fmt.Errorf("throttled")
+		}
+
+		ch <- prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 1, accountID)
+
+		return nil
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	err := h.CollectForAccounts(ch, "test", collectFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var collected []string
+	up := map[string]float64{}
+	for m := range ch {
+		var pb dto.Metric
+		err := m.Write(&pb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		switch m.Desc() {
+		case testDesc:
+			collected = append(collected, labels[labelAccountID])
+		case collectorAccountUpDesc:
+			up[labels[labelAccountID]+"/"+labels[labelRegion]] = pb.Gauge.GetValue()
+		}
+	}
+
+	expectedCollected := []string{"111111111111"}
+	if !cmp.Equal(collected, expectedCollected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedCollected, collected))
+	}
+
+	expectedUp := map[string]float64{
+		"111111111111/eu-central-1": 1,
+		"222222222222/us-east-1":    0,
+		"333333333333/eu-central-1": 0,
+	}
+	if !cmp.Equal(up, expectedUp) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedUp, up))
+	}
+
+	expectedErrors := map[string]float64{
+		"111111111111/eu-central-1": 0,
+		"222222222222/us-east-1":    1,
+		"333333333333/eu-central-1": 1,
+	}
+	for k, expected := range expectedErrors {
+		parts := strings.Split(k, "/")
+		count := testutil.ToFloat64(h.accountErrors.WithLabelValues(parts[0], "test", parts[1]))
+		if count != expected {
+			t.Fatalf("expected %v errors for %s, got %v", expected, k, count)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package collector
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -36,6 +35,7 @@ var (
 			labelAccountID,
 			labelVPC,
 			labelAZ,
+			labelRegion,
 		},
 		nil,
 	)
@@ -103,9 +103,9 @@ func newNATCache(expiration time.Duration) *natCache {
 	return cache
 }
 
-func (n *natCache) Get(accID string) (*natInfoResponse, error) {
+func (n *natCache) Get(accountID string, region string) (*natInfoResponse, error) {
 	var c natInfoResponse
-	raw, exists := n.cache.Get(getNATCacheKey(accID))
+	raw, exists := n.cache.Get(getNATCacheKey(accountID, region))
 	if exists {
 		err := json.Unmarshal(raw, &c)
 		if err != nil {
@@ -116,41 +116,27 @@ func (n *natCache) Get(accID string) (*natInfoResponse, error) {
 	return &c, nil
 }
 
-func (n *natCache) Set(accID string, content natInfoResponse) error {
+func (n *natCache) Set(accountID string, region string, content natInfoResponse) error {
 	contentSerialized, err := json.Marshal(content)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	n.cache.Set(getNATCacheKey(accID), contentSerialized)
+	n.cache.Set(getNATCacheKey(accountID, region), contentSerialized)
 
 	return nil
 }
 
-func getNATCacheKey(accID string) string {
-	return prefixNATcacheKey + accID
+func getNATCacheKey(accountID string, region string) string {
+	return prefixNATcacheKey + accountID + "/" + region
 }
 
 func (v *NAT) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := v.helper.ListReconciledClusters()
+	err := v.helper.CollectForAccounts(ch, subsystemNAT, v.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	awsClientsList, err := v.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		err := v.collectForAccount(ch, awsClients)
-		if err != nil {
-			return microerror.Mask(err)
-		}
-	}
-
 	return nil
 }
 
@@ -159,15 +145,10 @@ func (v *NAT) Describe(ch chan<- *prometheus.Desc) error {
 	return nil
 }
 
-func (v *NAT) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	accountID, err := v.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+func (v *NAT) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
 	var natInfo *natInfoResponse
 	// Check if response is cached
-	natInfo, err = v.cache.Get(accountID)
+	natInfo, err := v.cache.Get(accountID, awsClients.Region)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -179,7 +160,7 @@ func (v *NAT) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 			return microerror.Mask(err)
 		}
 
-		err = v.cache.Set(accountID, *natInfo)
+		err = v.cache.Set(accountID, awsClients.Region, *natInfo)
 		if err != nil {
 			return microerror.Mask(err)
 		}
@@ -195,6 +176,7 @@ func (v *NAT) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 					accountID,
 					vpcID,
 					azName,
+					awsClients.Region,
 				)
 			}
 		}
@@ -220,13 +202,22 @@ func getNatInfoFromAPI(accountID string, awsClients clientaws.Clients) (*natInfo
 			},
 		},
 	}
-	rv, err := awsClients.EC2.DescribeVpcs(iv)
-	if err != nil {
-		return nil, microerror.Mask(err)
+	var vpcs []*ec2.Vpc
+	for {
+		rv, err := awsClients.EC2.DescribeVpcs(iv)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		vpcs = append(vpcs, rv.Vpcs...)
+
+		if rv.NextToken == nil {
+			break
+		}
+		iv.SetNextToken(*rv.NextToken)
 	}
 
 	// 2. Get all NAT GWs for each VPC
-	for _, vpc := range rv.Vpcs {
+	for _, vpc := range vpcs {
 		vpcID := *vpc.VpcId
 		res.Vpcs[vpcID] = vpcInfo{
 			NatGatewaysByZone: make(map[string]float64),
@@ -242,13 +233,22 @@ func getNatInfoFromAPI(accountID string, awsClients clientaws.Clients) (*natInfo
 				},
 			},
 		}
-		rn, err := awsClients.EC2.DescribeNatGateways(in)
-		if err != nil {
-			return nil, microerror.Mask(err)
+		var natGateways []*ec2.NatGateway
+		for {
+			rn, err := awsClients.EC2.DescribeNatGateways(in)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			natGateways = append(natGateways, rn.NatGateways...)
+
+			if rn.NextToken == nil {
+				break
+			}
+			in.SetNextToken(*rn.NextToken)
 		}
 
 		// 3. Get all the subnets for each NAT GW
-		for _, nat := range rn.NatGateways {
+		for _, nat := range natGateways {
 			is := &ec2.DescribeSubnetsInput{
 				SubnetIds: []*string{
 					nat.SubnetId,
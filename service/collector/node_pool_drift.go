@@ -0,0 +1,416 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	// labelField is the metric's label key that will hold the name of the
+	// compared field.
+	labelField = "field"
+)
+
+const (
+	// subsystemNodePoolDrift will become the second part of the metric name,
+	// right after namespace.
+	subsystemNodePoolDrift = "node_pool_drift"
+)
+
+const (
+	driftFieldAvailabilityZones    = "availability_zones"
+	driftFieldInstanceType         = "instance_type"
+	driftFieldMaxSize              = "max_size"
+	driftFieldMinSize              = "min_size"
+	driftFieldOnDemandBaseCapacity = "on_demand_base_capacity"
+	driftFieldOnDemandPercentage   = "on_demand_percentage_above_base_capacity"
+	driftFieldSpotAllocation       = "spot_allocation_strategy"
+	driftFieldSpotMaxPrice         = "spot_max_price"
+)
+
+// nodePoolCreationGracePeriod is the time after the creation of an
+// AWSMachineDeployment CR within which its ASG is not reported missing, since
+// the ASG is only created along with the node pool's CloudFormation stack.
+const nodePoolCreationGracePeriod = 30 * time.Minute
+
+// defaultOnDemandPercentageAboveBaseCapacity is used by AWS as well as by the
+// aws-operator if no percentage is configured.
+const defaultOnDemandPercentageAboveBaseCapacity = 100
+
+// nodePoolSpotAllocationStrategy and nodePoolSpotMaxPrice are the spot
+// settings the aws-operator configures for every AWSMachineDeployment, since
+// the CR has no fields for them. The allocation strategy is the AWS default
+// as well, and an empty max price caps spot prices at the on-demand price.
+const (
+	nodePoolSpotAllocationStrategy = "lowest-price"
+	nodePoolSpotMaxPrice           = ""
+)
+
+var (
+	nodePoolDriftMismatchDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemNodePoolDrift, "mismatch"),
+		"Gauge about fields of a node pool's ASG differing from its AWSMachineDeployment CR. 1 = differs",
+		[]string{
+			labelAccountID,
+			labelASG,
+			labelCluster,
+			labelNodepool,
+			labelField,
+			labelRegion,
+		},
+		nil,
+	)
+
+	nodePoolDriftMissingASGDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemNodePoolDrift, "missing_asg"),
+		"Node pools of the installation which have an AWSMachineDeployment CR but no ASG in the region of their cluster.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+
+	nodePoolDriftMissingCRDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemNodePoolDrift, "missing_cr"),
+		"Node pools of the installation which have an ASG but no AWSMachineDeployment CR.",
+		[]string{
+			labelAccountID,
+			labelASG,
+			labelCluster,
+			labelNodepool,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type NodePoolDriftConfig struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+}
+
+// NodePoolDrift compares the ASGs of the installation's node pools with their
+// AWSMachineDeployment CRs, e.g. in order to find scaling limits or instance
+// types which were changed in the AWS console.
+type NodePoolDrift struct {
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+	// now is only meant to be replaced in tests.
+	now func() time.Time
+}
+
+func NewNodePoolDrift(config NodePoolDriftConfig) (*NodePoolDrift, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	n := &NodePoolDrift{
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+		now:              time.Now,
+	}
+
+	return n, nil
+}
+
+func (n *NodePoolDrift) Collect(ch chan<- prometheus.Metric) error {
+	d, err := n.helper.Discovery(context.Background())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	machineDeployments := map[string]infrastructurev1alpha3.AWSMachineDeployment{}
+	for _, md := range d.MachineDeployments.Items {
+		machineDeployments[key.MachineDeploymentID(md)] = md
+	}
+
+	clusterRegions := map[string]string{}
+	for _, cluster := range d.Clusters.Items {
+		clusterRegions[key.ClusterID(cluster)] = cluster.Spec.Provider.Region
+	}
+
+	// A node pool without ASG might only look like one because the ASGs of
+	// its account or region could not be listed. Missing ASGs are therefore
+	// only reported if the ASGs of all accounts were listed, and only in the
+	// regions they were listed in.
+	var mutex sync.Mutex
+	complete := len(d.Accounts.Errors) == 0
+	found := map[string]bool{}
+	regions := map[string]bool{}
+
+	collectFunc := func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		ids, err := n.collectForAccount(ch, awsClients, accountID, machineDeployments)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err != nil {
+			complete = false
+			return microerror.Mask(err)
+		}
+		for _, id := range ids {
+			found[id] = true
+		}
+		regions[awsClients.Region] = true
+
+		return nil
+	}
+
+	err = n.helper.CollectForAccounts(ch, subsystemNodePoolDrift, collectFunc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if complete {
+		for id, md := range machineDeployments {
+			if found[id] {
+				continue
+			}
+
+			clusterID := key.MachineDeploymentClusterID(md)
+			if !missingASG(md, clusterRegions[clusterID], regions, n.now()) {
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				nodePoolDriftMissingASGDesc,
+				prometheus.GaugeValue,
+				GaugeValue,
+				d.Accounts.ClusterAccounts[clusterID],
+				clusterID,
+				id,
+				clusterRegions[clusterID],
+			)
+		}
+	}
+
+	return nil
+}
+
+func (n *NodePoolDrift) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- nodePoolDriftMismatchDesc
+	ch <- nodePoolDriftMissingASGDesc
+	ch <- nodePoolDriftMissingCRDesc
+	return nil
+}
+
+// collectForAccount compares the node pool ASGs of the installation with the
+// given AWSMachineDeployment CRs, keyed by node pool ID. It returns the IDs of
+// the node pools it found an ASG for.
+func (n *NodePoolDrift) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string, machineDeployments map[string]infrastructurev1alpha3.AWSMachineDeployment) ([]string, error) {
+	var found []string
+
+	i := &autoscaling.DescribeAutoScalingGroupsInput{}
+	for {
+		o, err := awsClients.AutoScaling.DescribeAutoScalingGroups(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, asg := range o.AutoScalingGroups {
+			var cluster, installation, nodePool string
+
+			for _, tag := range asg.Tags {
+				switch aws.StringValue(tag.Key) {
+				case tagCluster:
+					cluster = aws.StringValue(tag.Value)
+				case key.TagInstallation:
+					installation = aws.StringValue(tag.Value)
+				case key.TagMachineDeployment:
+					nodePool = aws.StringValue(tag.Value)
+				}
+			}
+
+			// ASGs of the control plane are not managed by an
+			// AWSMachineDeployment.
+			if installation != n.installationName || nodePool == "" {
+				continue
+			}
+
+			md, ok := machineDeployments[nodePool]
+			if !ok {
+				ch <- prometheus.MustNewConstMetric(
+					nodePoolDriftMissingCRDesc,
+					prometheus.GaugeValue,
+					GaugeValue,
+					accountID,
+					aws.StringValue(asg.AutoScalingGroupName),
+					cluster,
+					nodePool,
+					awsClients.Region,
+				)
+
+				continue
+			}
+
+			found = append(found, nodePool)
+
+			for field, drift := range nodePoolDrift(md, asg) {
+				var value float64
+				if drift {
+					value = GaugeValue
+				}
+
+				ch <- prometheus.MustNewConstMetric(
+					nodePoolDriftMismatchDesc,
+					prometheus.GaugeValue,
+					value,
+					accountID,
+					aws.StringValue(asg.AutoScalingGroupName),
+					cluster,
+					nodePool,
+					field,
+					awsClients.Region,
+				)
+			}
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return found, nil
+}
+
+// missingASG returns whether the ASG of the given node pool, which was not
+// found in any of the given collected regions, is missing. The ASG can only be
+// missing if the region of the node pool's cluster was collected. Node pools
+// being created or deleted are never missing their ASG.
+func missingASG(md infrastructurev1alpha3.AWSMachineDeployment, region string, regions map[string]bool, now time.Time) bool {
+	if md.GetDeletionTimestamp() != nil {
+		return false
+	}
+	if now.Sub(md.GetCreationTimestamp().Time) < nodePoolCreationGracePeriod {
+		return false
+	}
+
+	return region != "" && regions[region]
+}
+
+// nodePoolDrift returns for every compared field whether the given ASG differs
+// from the given AWSMachineDeployment CR.
+func nodePoolDrift(md infrastructurev1alpha3.AWSMachineDeployment, asg *autoscaling.Group) map[string]bool {
+	// ASGs without mixed instances policy only run on-demand instances.
+	var onDemandBaseCapacity, onDemandPercentage int64 = 0, defaultOnDemandPercentageAboveBaseCapacity
+	spotAllocationStrategy, spotMaxPrice := nodePoolSpotAllocationStrategy, nodePoolSpotMaxPrice
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.InstancesDistribution != nil {
+		distribution := asg.MixedInstancesPolicy.InstancesDistribution
+		onDemandBaseCapacity = aws.Int64Value(distribution.OnDemandBaseCapacity)
+		if distribution.OnDemandPercentageAboveBaseCapacity != nil {
+			onDemandPercentage = *distribution.OnDemandPercentageAboveBaseCapacity
+		}
+		if distribution.SpotAllocationStrategy != nil {
+			spotAllocationStrategy = *distribution.SpotAllocationStrategy
+		}
+		spotMaxPrice = aws.StringValue(distribution.SpotMaxPrice)
+	}
+
+	desiredOnDemandPercentage := int64(defaultOnDemandPercentageAboveBaseCapacity)
+	if p := md.Spec.Provider.InstanceDistribution.OnDemandPercentageAboveBaseCapacity; p != nil {
+		desiredOnDemandPercentage = int64(*p)
+	}
+
+	return map[string]bool{
+		driftFieldAvailabilityZones:    !equalStringSets(md.Spec.Provider.AvailabilityZones, aws.StringValueSlice(asg.AvailabilityZones)),
+		driftFieldInstanceType:         instanceTypeDrift(md.Spec.Provider.Worker, asgInstanceTypes(asg)),
+		driftFieldMaxSize:              int64(md.Spec.NodePool.Scaling.Max) != aws.Int64Value(asg.MaxSize),
+		driftFieldMinSize:              int64(md.Spec.NodePool.Scaling.Min) != aws.Int64Value(asg.MinSize),
+		driftFieldOnDemandBaseCapacity: int64(md.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity) != onDemandBaseCapacity,
+		driftFieldOnDemandPercentage:   desiredOnDemandPercentage != onDemandPercentage,
+		driftFieldSpotAllocation:       spotAllocationStrategy != nodePoolSpotAllocationStrategy,
+		driftFieldSpotMaxPrice:         spotMaxPrice != nodePoolSpotMaxPrice,
+	}
+}
+
+// asgInstanceTypes returns the instance types the given ASG launches. These
+// are the launch template overrides of its mixed instances policy, or the
+// instance types of its current instances if it has no such overrides.
+func asgInstanceTypes(asg *autoscaling.Group) []string {
+	types := map[string]bool{}
+
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		for _, o := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+			types[aws.StringValue(o.InstanceType)] = true
+		}
+	}
+	if len(types) == 0 {
+		for _, instance := range asg.Instances {
+			types[aws.StringValue(instance.InstanceType)] = true
+		}
+	}
+
+	var list []string
+	for t := range types {
+		if t != "" {
+			list = append(list, t)
+		}
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// instanceTypeDrift returns whether the given instance types of an ASG differ
+// from the configured worker instance type. Node pools using alike instance
+// types may launch additional types, as long as the configured one is among
+// them. Without any known instance type no drift can be detected.
+func instanceTypeDrift(worker infrastructurev1alpha3.AWSMachineDeploymentSpecProviderWorker, types []string) bool {
+	if len(types) == 0 {
+		return false
+	}
+
+	if worker.UseAlikeInstanceTypes {
+		return !containsString(types, worker.InstanceType)
+	}
+
+	return len(types) != 1 || types[0] != worker.InstanceType
+}
+
+func equalStringSets(a []string, b []string) bool {
+	set := map[string]bool{}
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		if !set[s] {
+			return false
+		}
+	}
+
+	other := map[string]bool{}
+	for _, s := range b {
+		other[s] = true
+	}
+
+	return len(set) == len(other)
+}
@@ -0,0 +1,286 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/apiextensions/v3/pkg/label"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+func testMachineDeployment(cluster string, id string, instanceType string, zones []string) infrastructurev1alpha3.AWSMachineDeployment {
+	md := infrastructurev1alpha3.AWSMachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id,
+			Labels: map[string]string{
+				label.Cluster:           cluster,
+				label.MachineDeployment: id,
+			},
+		},
+	}
+	md.Spec.NodePool.Scaling.Min = 3
+	md.Spec.NodePool.Scaling.Max = 10
+	md.Spec.Provider.AvailabilityZones = zones
+	md.Spec.Provider.Worker.InstanceType = instanceType
+
+	return md
+}
+
+func testNodePoolASG(name string, cluster string, nodePool string, instanceTypes []string, zones []string) *autoscaling.Group {
+	var overrides []*autoscaling.LaunchTemplateOverrides
+	for _, t := range instanceTypes {
+		overrides = append(overrides, &autoscaling.LaunchTemplateOverrides{InstanceType: aws.String(t)})
+	}
+
+	return &autoscaling.Group{
+		AutoScalingGroupName: aws.String(name),
+		AvailabilityZones:    aws.StringSlice(zones),
+		MaxSize:              aws.Int64(10),
+		MinSize:              aws.Int64(3),
+		MixedInstancesPolicy: &autoscaling.MixedInstancesPolicy{
+			InstancesDistribution: &autoscaling.InstancesDistribution{
+				OnDemandBaseCapacity:                aws.Int64(0),
+				OnDemandPercentageAboveBaseCapacity: aws.Int64(100),
+				SpotAllocationStrategy:              aws.String("lowest-price"),
+			},
+			LaunchTemplate: &autoscaling.LaunchTemplate{
+				Overrides: overrides,
+			},
+		},
+		Tags: append(
+			asgTags("test", cluster),
+			&autoscaling.TagDescription{Key: aws.String(key.TagMachineDeployment), Value: aws.String(nodePool)},
+		),
+	}
+}
+
+func Test_NodePoolDrift_collectForAccount(t *testing.T) {
+	zones := []string{"eu-central-1a", "eu-central-1b"}
+
+	// The ASG of a1b2c matches its CR.
+	a1b2c := testMachineDeployment("al9qy", "a1b2c", "m5.xlarge", zones)
+
+	// The ASG of d3e4f was scaled up, moved to another availability zone and
+	// got spot instances with a capacity optimized allocation strategy and a
+	// max price, all in the AWS console.
+	d3e4f := testMachineDeployment("al9qy", "d3e4f", "m5.xlarge", zones)
+	d3e4fASG := testNodePoolASG("al9qy-tcnp-d3e4f", "al9qy", "d3e4f", []string{"m5.xlarge"}, []string{"eu-central-1a", "eu-central-1c"})
+	d3e4fASG.MaxSize = aws.Int64(20)
+	d3e4fASG.MixedInstancesPolicy.InstancesDistribution.OnDemandPercentageAboveBaseCapacity = aws.Int64(50)
+	d3e4fASG.MixedInstancesPolicy.InstancesDistribution.SpotAllocationStrategy = aws.String("capacity-optimized")
+	d3e4fASG.MixedInstancesPolicy.InstancesDistribution.SpotMaxPrice = aws.String("0.1")
+
+	// Node pools using alike instance types may launch other types as well,
+	// but not without the configured one.
+	g5h6i := testMachineDeployment("al9qy", "g5h6i", "r5.xlarge", zones)
+	g5h6i.Spec.Provider.Worker.UseAlikeInstanceTypes = true
+
+	machineDeployments := map[string]infrastructurev1alpha3.AWSMachineDeployment{
+		"a1b2c": a1b2c,
+		"d3e4f": d3e4f,
+		"g5h6i": g5h6i,
+		// Node pools without ASG are not returned.
+		"j7k8l": testMachineDeployment("al9qy", "j7k8l", "m5.xlarge", zones),
+	}
+
+	awsClients := clientaws.Clients{
+		AutoScaling: &autoScalingMock{
+			groups: []*autoscaling.Group{
+				testNodePoolASG("al9qy-tcnp-a1b2c", "al9qy", "a1b2c", []string{"m5.xlarge"}, zones),
+				d3e4fASG,
+				testNodePoolASG("al9qy-tcnp-g5h6i", "al9qy", "g5h6i", []string{"m5.xlarge", "m5a.xlarge"}, zones),
+				// The CR of this node pool was deleted.
+				testNodePoolASG("al9qy-tcnp-m9n0o", "al9qy", "m9n0o", []string{"m5.xlarge"}, zones),
+				// ASGs of the control plane are ignored.
+				{
+					AutoScalingGroupName: aws.String("al9qy-tccpn"),
+					Tags:                 asgTags("test", "al9qy"),
+				},
+			},
+		},
+		Region: "eu-central-1",
+	}
+
+	n, err := NewNodePoolDrift(NodePoolDriftConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found []string
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			var err error
+			found, err = n.collectForAccount(ch, awsClients, "000000000000", machineDeployments)
+			return err
+		},
+		describe: n.Describe,
+	}
+
+	compareGolden(t, "node_pool_drift", gatherText(t, c))
+
+	expected := []string{"a1b2c", "d3e4f", "g5h6i"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("expected %v, got %v", expected, found)
+	}
+}
+
+// Test_NodePoolDrift_Collect_MissingASG ensures that node pools without ASG
+// are reported with the account and region of their cluster.
+func Test_NodePoolDrift_Collect_MissingASG(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h := newTestHelper(t, fake.NewFakeClientWithScheme(newTestScheme(t)))
+	h.now = func() time.Time { return now }
+
+	cluster := newTestCluster("al9qy", "credential-first")
+	cluster.Spec.Provider.Region = "eu-central-1"
+
+	md := testMachineDeployment("al9qy", "a1b2c", "m5.xlarge", []string{"eu-central-1a"})
+	md.SetCreationTimestamp(metav1.NewTime(now.Add(-time.Hour)))
+
+	h.discovery = &discovery{
+		Accounts: &awsAccounts{
+			Clients: map[string][]clientaws.Clients{
+				"111111111111": {{AutoScaling: &autoScalingMock{}, Region: "eu-central-1"}},
+			},
+			ClusterAccounts: map[string]string{
+				"al9qy": "111111111111",
+			},
+		},
+		Clusters: &infrastructurev1alpha3.AWSClusterList{
+			Items: []infrastructurev1alpha3.AWSCluster{*cluster},
+		},
+		MachineDeployments: &infrastructurev1alpha3.AWSMachineDeploymentList{
+			Items: []infrastructurev1alpha3.AWSMachineDeployment{md},
+		},
+		Time: now,
+	}
+
+	n, err := NewNodePoolDrift(NodePoolDriftConfig{
+		Helper: h,
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.now = h.now
+
+	ch := make(chan prometheus.Metric, 100)
+	err = n.Collect(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var missing []map[string]string
+	for m := range ch {
+		if m.Desc() != nodePoolDriftMissingASGDesc {
+			continue
+		}
+
+		var pb dto.Metric
+		err := m.Write(&pb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		missing = append(missing, labels)
+	}
+
+	expected := []map[string]string{
+		{
+			labelAccountID: "111111111111",
+			labelCluster:   "al9qy",
+			labelNodepool:  "a1b2c",
+			labelRegion:    "eu-central-1",
+		},
+	}
+	if !cmp.Equal(missing, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, missing))
+	}
+}
+
+func Test_missingASG(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	regions := map[string]bool{"eu-central-1": true}
+
+	testCases := []struct {
+		name     string
+		created  time.Time
+		deleted  bool
+		region   string
+		expected bool
+	}{
+		{
+			name:     "case 0: node pool in a collected region",
+			created:  now.Add(-time.Hour),
+			region:   "eu-central-1",
+			expected: true,
+		},
+		{
+			name:     "case 1: node pool in a region which was not collected",
+			created:  now.Add(-time.Hour),
+			region:   "us-east-1",
+			expected: false,
+		},
+		{
+			name:     "case 2: node pool of an unknown cluster",
+			created:  now.Add(-time.Hour),
+			region:   "",
+			expected: false,
+		},
+		{
+			name:     "case 3: node pool being created",
+			created:  now.Add(-time.Minute),
+			region:   "eu-central-1",
+			expected: false,
+		},
+		{
+			name:     "case 4: node pool being deleted",
+			created:  now.Add(-time.Hour),
+			deleted:  true,
+			region:   "eu-central-1",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			md := testMachineDeployment("al9qy", "a1b2c", "m5.xlarge", []string{"eu-central-1a"})
+			md.SetCreationTimestamp(metav1.NewTime(tc.created))
+			if tc.deleted {
+				deleted := metav1.NewTime(now)
+				md.SetDeletionTimestamp(&deleted)
+			}
+
+			missing := missingASG(md, tc.region, regions, now)
+			if missing != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	labelResourceType = "resource_type"
+)
+
+const (
+	subsystemOrphaned = "orphaned"
+)
+
+const (
+	// orphanedGracePeriod is the time a cluster's resources have to be
+	// orphaned before they are reported. AWS resources of a cluster outlive
+	// its AWSCluster CR for a while on deletion, and they can be listed before
+	// the discovery snapshot contains a newly created cluster.
+	orphanedGracePeriod = 30 * time.Minute
+)
+
+var (
+	orphanedResourcesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemOrphaned, "resources"),
+		"Number of AWS resources of the installation belonging to a cluster which no longer exists.",
+		[]string{
+			labelAccountID,
+			labelResourceType,
+			labelCluster,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type OrphanedConfig struct {
+	Helper        *helper
+	Logger        micrologger.Logger
+	ResourceCache *resourceCache
+
+	InstallationName string
+}
+
+// Orphaned collects the number of AWS resources of the installation which are
+// tagged with the ID of a cluster that has no AWSCluster CR anymore, e.g.
+// because they were leaked on cluster deletion. Clusters are only reported
+// once their resources were orphaned for orphanedGracePeriod, so that clusters
+// being created or deleted do not flap. The grace period starts over when the
+// collector restarts.
+type Orphaned struct {
+	helper        *helper
+	logger        micrologger.Logger
+	resourceCache *resourceCache
+
+	installationName string
+	// mutex guards orphanedSince, which is written by the account collectors
+	// running concurrently.
+	mutex sync.Mutex
+	// orphanedSince holds the time the resources of a cluster were first
+	// found orphaned in an account and region.
+	orphanedSince map[orphanedCluster]time.Time
+	// now is only meant to be replaced in tests.
+	now func() time.Time
+}
+
+// orphanedCluster identifies the orphaned resources of a cluster in one region
+// of one AWS account.
+type orphanedCluster struct {
+	AccountID string
+	ClusterID string
+	Region    string
+}
+
+func NewOrphaned(config OrphanedConfig) (*Orphaned, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.ResourceCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ResourceCache must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	o := &Orphaned{
+		helper:        config.Helper,
+		logger:        config.Logger,
+		resourceCache: config.ResourceCache,
+
+		installationName: config.InstallationName,
+		orphanedSince:    map[orphanedCluster]time.Time{},
+		now:              time.Now,
+	}
+
+	return o, nil
+}
+
+func (o *Orphaned) Collect(ch chan<- prometheus.Metric) error {
+	d, err := o.helper.Discovery(context.Background())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	clusters := map[string]bool{}
+	for _, cluster := range d.Clusters.Items {
+		clusters[key.ClusterID(cluster)] = true
+	}
+
+	// Clusters which are not found orphaned anymore are forgotten, so that
+	// their grace period starts over if they are ever orphaned again. This is
+	// only known for the accounts and regions whose resources were listed.
+	var mutex sync.Mutex
+	found := map[orphanedCluster]bool{}
+	listed := map[accountCollector]bool{}
+
+	collectFunc := func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		ids, err := o.collectForAccount(ch, awsClients, accountID, clusters)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for _, id := range ids {
+			found[orphanedCluster{AccountID: accountID, ClusterID: id, Region: awsClients.Region}] = true
+		}
+		listed[accountCollector{AccountID: accountID, Collector: subsystemOrphaned, Region: awsClients.Region}] = true
+
+		return nil
+	}
+
+	err = o.helper.CollectForAccounts(ch, subsystemOrphaned, collectFunc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for c := range o.orphanedSince {
+		if !listed[accountCollector{AccountID: c.AccountID, Collector: subsystemOrphaned, Region: c.Region}] {
+			continue
+		}
+		if !found[c] {
+			delete(o.orphanedSince, c)
+		}
+	}
+
+	return nil
+}
+
+func (o *Orphaned) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- orphanedResourcesDesc
+	return nil
+}
+
+// collectForAccount emits the number of orphaned resources per resource type
+// and cluster. Clusters are orphaned if they are not contained in the given
+// set of existing clusters. It returns the IDs of all orphaned clusters found,
+// including the ones still within their grace period.
+func (o *Orphaned) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string, clusters map[string]bool) ([]string, error) {
+	orphaned := map[string]map[string]float64{}
+	for resourceType := range managedResourceTypes {
+		resources, err := o.resourceCache.List(awsClients, accountID, resourceType)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// Resources of the installation not belonging to any cluster are
+		// never orphaned.
+		for _, tags := range resources {
+			id := tags[tagCluster]
+			if tags[key.TagInstallation] != o.installationName || id == "" || clusters[id] {
+				continue
+			}
+			if orphaned[id] == nil {
+				orphaned[id] = map[string]float64{}
+			}
+			orphaned[id][resourceType]++
+		}
+	}
+
+	var ids []string
+	for id, counts := range orphaned {
+		ids = append(ids, id)
+
+		if !o.gracePeriodExpired(orphanedCluster{AccountID: accountID, ClusterID: id, Region: awsClients.Region}) {
+			continue
+		}
+
+		for resourceType, count := range counts {
+			ch <- prometheus.MustNewConstMetric(
+				orphanedResourcesDesc,
+				prometheus.GaugeValue,
+				count,
+				accountID,
+				resourceType,
+				id,
+				awsClients.Region,
+			)
+		}
+	}
+
+	return ids, nil
+}
+
+// gracePeriodExpired returns whether the resources of the given cluster were
+// first found orphaned longer than orphanedGracePeriod ago. The first call for
+// a cluster starts its grace period.
+func (o *Orphaned) gracePeriodExpired(c orphanedCluster) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	since, ok := o.orphanedSince[c]
+	if !ok {
+		since = o.now()
+		o.orphanedSince[c] = since
+	}
+
+	return o.now().Sub(since) >= orphanedGracePeriod
+}
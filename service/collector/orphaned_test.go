@@ -0,0 +1,296 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type cloudFormationMock struct {
+	cloudformationiface.CloudFormationAPI
+
+	stacks []*cloudformation.Stack
+	// err is returned by DescribeStacks if set.
+	err error
+}
+
+func (c *cloudFormationMock) DescribeStacks(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &cloudformation.DescribeStacksOutput{Stacks: c.stacks}, nil
+}
+
+// taggedEC2Mock returns one resource of every kind per tag set, filtered by
+// the tag filters of the input like the AWS API does.
+type taggedEC2Mock struct {
+	ec2iface.EC2API
+
+	tags [][]*ec2.Tag
+}
+
+func (e *taggedEC2Mock) DescribeAddresses(i *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+	o := &ec2.DescribeAddressesOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filters) {
+			o.Addresses = append(o.Addresses, &ec2.Address{Tags: tags})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *taggedEC2Mock) DescribeInstances(i *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	o := &ec2.DescribeInstancesOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filters) {
+			o.Reservations = append(o.Reservations, &ec2.Reservation{
+				Instances: []*ec2.Instance{{Tags: tags}},
+			})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *taggedEC2Mock) DescribeNatGateways(i *ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	o := &ec2.DescribeNatGatewaysOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filter) {
+			o.NatGateways = append(o.NatGateways, &ec2.NatGateway{Tags: tags})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *taggedEC2Mock) DescribeSubnets(i *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	o := &ec2.DescribeSubnetsOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filters) {
+			o.Subnets = append(o.Subnets, &ec2.Subnet{Tags: tags})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *taggedEC2Mock) DescribeVolumes(i *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	o := &ec2.DescribeVolumesOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filters) {
+			o.Volumes = append(o.Volumes, &ec2.Volume{Tags: tags})
+		}
+	}
+
+	return o, nil
+}
+
+func (e *taggedEC2Mock) DescribeVpcs(i *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	o := &ec2.DescribeVpcsOutput{}
+	for _, tags := range e.tags {
+		if hasTags(tags, i.Filters) {
+			o.Vpcs = append(o.Vpcs, &ec2.Vpc{Tags: tags})
+		}
+	}
+
+	return o, nil
+}
+
+func Test_Orphaned_collectForAccount(t *testing.T) {
+	awsClients := clientaws.Clients{
+		CloudFormation: &cloudFormationMock{
+			stacks: []*cloudformation.Stack{
+				{
+					StackName: aws.String("cluster-al9qy-tccp"),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+						{Key: aws.String(tagCluster), Value: aws.String("al9qy")},
+					},
+				},
+				{
+					StackName: aws.String("cluster-x7k2e-tccp"),
+					Tags: []*cloudformation.Tag{
+						{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+						{Key: aws.String(tagCluster), Value: aws.String("x7k2e")},
+					},
+				},
+			},
+		},
+		EC2: &taggedEC2Mock{
+			tags: [][]*ec2.Tag{
+				ec2Tags("test", "al9qy"),
+				ec2Tags("test", "x7k2e"),
+				ec2Tags("test", "x7k2e"),
+				// Resources of other installations are ignored even if their
+				// cluster does not exist here.
+				ec2Tags("other", "b4c1d"),
+				// Resources of the installation not belonging to any cluster
+				// are never orphaned.
+				{
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+				},
+			},
+		},
+		ELB: &elbMock{
+			tags: map[string][]*elb.Tag{
+				"al9qy-api": elbTags("test", "al9qy"),
+				"x7k2e-api": elbTags("test", "x7k2e"),
+				"b4c1d-api": elbTags("other", "b4c1d"),
+			},
+		},
+		ELBv2: &elbv2Mock{
+			loadBalancers: []*elbv2.LoadBalancer{
+				{LoadBalancerArn: aws.String("arn-x7k2e")},
+			},
+			tags: map[string][]*elbv2.Tag{
+				"arn-x7k2e": {
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+					{Key: aws.String(tagCluster), Value: aws.String("x7k2e")},
+				},
+			},
+		},
+		Region: "eu-central-1",
+	}
+
+	o, err := NewOrphaned(OrphanedConfig{
+		Helper:        &helper{},
+		Logger:        microloggertest.New(),
+		ResourceCache: newResourceCache(time.Minute),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	o.now = func() time.Time { return now }
+
+	clusters := map[string]bool{"al9qy": true}
+
+	// Orphaned resources are not reported within the grace period.
+	{
+		ch := make(chan prometheus.Metric, 100)
+		ids, err := o.collectForAccount(ch, awsClients, "000000000000", clusters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ch) != 0 {
+			t.Fatalf("expected no metrics within the grace period, got %d", len(ch))
+		}
+		if len(ids) != 1 || ids[0] != "x7k2e" {
+			t.Fatalf("expected orphaned cluster x7k2e, got %v", ids)
+		}
+	}
+
+	now = now.Add(orphanedGracePeriod)
+
+	// The resources are cached, so no resources are listed anymore.
+	cached := clientaws.Clients{
+		CloudFormation: &cloudFormationMock{},
+		EC2:            &taggedEC2Mock{},
+		ELB:            &elbMock{},
+		ELBv2:          &elbv2Mock{},
+		Region:         "eu-central-1",
+	}
+
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			_, err := o.collectForAccount(ch, cached, "000000000000", clusters)
+			return err
+		},
+		describe: o.Describe,
+	}
+
+	compareGolden(t, "orphaned_resources", gatherText(t, c))
+}
+
+// Test_Orphaned_Collect_FailedAccounts ensures that the grace periods of
+// clusters are only forgotten in the accounts and regions whose resources
+// were listed.
+func Test_Orphaned_Collect_FailedAccounts(t *testing.T) {
+	emptyClients := func(region string) clientaws.Clients {
+		return clientaws.Clients{
+			CloudFormation: &cloudFormationMock{},
+			EC2:            &taggedEC2Mock{},
+			ELB:            &elbMock{},
+			ELBv2:          &elbv2Mock{},
+			Region:         region,
+		}
+	}
+
+	failing := emptyClients("eu-central-1")
+	failing.CloudFormation = &cloudFormationMock{err: fmt.Errorf("throttled")}
+
+	h := newTestHelper(t, fake.NewFakeClientWithScheme(newTestScheme(t)))
+	h.discovery = &discovery{
+		Accounts: &awsAccounts{
+			Clients: map[string][]clientaws.Clients{
+				"111111111111": {failing, emptyClients("us-east-1")},
+			},
+			Errors: map[string]accountError{
+				"222222222222": {
+					Err:     fmt.Errorf("access denied"),
+					Regions: []string{"eu-central-1"},
+				},
+			},
+		},
+		Clusters: &infrastructurev1alpha3.AWSClusterList{},
+		Time:     h.now(),
+	}
+
+	o, err := NewOrphaned(OrphanedConfig{
+		Helper:        h,
+		Logger:        microloggertest.New(),
+		ResourceCache: newResourceCache(time.Minute),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	o.orphanedSince = map[orphanedCluster]time.Time{
+		// The resources of this region can not be listed.
+		{AccountID: "111111111111", ClusterID: "al9qy", Region: "eu-central-1"}: since,
+		// The resources of this region are gone.
+		{AccountID: "111111111111", ClusterID: "al9qy", Region: "us-east-1"}: since,
+		// The role of this account can not be assumed.
+		{AccountID: "222222222222", ClusterID: "x7k2e", Region: "eu-central-1"}: since,
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	err = o.Collect(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[orphanedCluster]time.Time{
+		{AccountID: "111111111111", ClusterID: "al9qy", Region: "eu-central-1"}: since,
+		{AccountID: "222222222222", ClusterID: "x7k2e", Region: "eu-central-1"}: since,
+	}
+	if !cmp.Equal(o.orphanedSince, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, o.orphanedSince))
+	}
+}
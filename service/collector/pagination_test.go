@@ -0,0 +1,338 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	// testPageSize is the number of items the paginated mocks return per
+	// page.
+	testPageSize = 2
+	// testPagedItems is the number of resources of every kind the paginated
+	// mocks hold, so that they are spread over several pages with the last
+	// page not being full.
+	testPagedItems = 5
+)
+
+// testPage returns the bounds of the page identified by the given token in a
+// list of n items, together with the token of the next page. The token is nil
+// on the last page.
+func testPage(t *testing.T, n int, token *string) (int, int, *string) {
+	var start int
+	if token != nil {
+		var err error
+		start, err = strconv.Atoi(*token)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	end := start + testPageSize
+	if end >= n {
+		return start, n, nil
+	}
+
+	return start, end, aws.String(strconv.Itoa(end))
+}
+
+func testInstallationTags() map[string]string {
+	return map[string]string{
+		key.TagInstallation: "test",
+		tagStack:            key.StackTCCP,
+	}
+}
+
+type cloudFormationPagedMock struct {
+	cloudformationiface.CloudFormationAPI
+
+	t *testing.T
+}
+
+func (c *cloudFormationPagedMock) DescribeStacks(i *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	start, end, next := testPage(c.t, testPagedItems, i.NextToken)
+
+	o := &cloudformation.DescribeStacksOutput{NextToken: next}
+	for n := start; n < end; n++ {
+		stack := &cloudformation.Stack{
+			StackId:     aws.String(fmt.Sprintf("stack-%d", n)),
+			StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+		}
+		for k, v := range testInstallationTags() {
+			stack.Tags = append(stack.Tags, &cloudformation.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		o.Stacks = append(o.Stacks, stack)
+	}
+
+	return o, nil
+}
+
+type ec2PagedMock struct {
+	ec2iface.EC2API
+
+	t *testing.T
+}
+
+func (e *ec2PagedMock) DescribeNatGateways(i *ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	// All NAT gateways are located in the last VPC, so that they are only
+	// found if all VPCs are listed.
+	lastVPC := fmt.Sprintf("vpc-%d", testPagedItems-1)
+	if aws.StringValue(i.Filter[0].Values[0]) != lastVPC {
+		return &ec2.DescribeNatGatewaysOutput{}, nil
+	}
+
+	start, end, next := testPage(e.t, testPagedItems, i.NextToken)
+
+	o := &ec2.DescribeNatGatewaysOutput{NextToken: next}
+	for n := start; n < end; n++ {
+		o.NatGateways = append(o.NatGateways, &ec2.NatGateway{
+			NatGatewayId: aws.String(fmt.Sprintf("nat-%d", n)),
+			SubnetId:     aws.String(fmt.Sprintf("subnet-%d", n)),
+			VpcId:        aws.String(lastVPC),
+		})
+	}
+
+	return o, nil
+}
+
+func (e *ec2PagedMock) DescribeSubnets(i *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	// Subnets described by ID are returned as a whole.
+	if len(i.SubnetIds) > 0 {
+		o := &ec2.DescribeSubnetsOutput{}
+		for _, id := range i.SubnetIds {
+			var n int
+			_, err := fmt.Sscanf(*id, "subnet-%d", &n)
+			if err != nil {
+				e.t.Fatal(err)
+			}
+			o.Subnets = append(o.Subnets, testSubnet(n))
+		}
+
+		return o, nil
+	}
+
+	start, end, next := testPage(e.t, testPagedItems, i.NextToken)
+
+	o := &ec2.DescribeSubnetsOutput{NextToken: next}
+	for n := start; n < end; n++ {
+		o.Subnets = append(o.Subnets, testSubnet(n))
+	}
+
+	return o, nil
+}
+
+func (e *ec2PagedMock) DescribeVpcs(i *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	start, end, next := testPage(e.t, testPagedItems, i.NextToken)
+
+	o := &ec2.DescribeVpcsOutput{NextToken: next}
+	for n := start; n < end; n++ {
+		vpc := &ec2.Vpc{
+			CidrBlock: aws.String("10.1.0.0/16"),
+			State:     aws.String(ec2.VpcStateAvailable),
+			VpcId:     aws.String(fmt.Sprintf("vpc-%d", n)),
+		}
+		for k, v := range testInstallationTags() {
+			vpc.Tags = append(vpc.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		o.Vpcs = append(o.Vpcs, vpc)
+	}
+
+	return o, nil
+}
+
+func (e *ec2PagedMock) DescribeVolumes(i *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	start, end, next := testPage(e.t, testPagedItems, i.NextToken)
+
+	o := &ec2.DescribeVolumesOutput{NextToken: next}
+	for n := start; n < end; n++ {
+		volume := &ec2.Volume{
+			State:    aws.String(ec2.VolumeStateInUse),
+			VolumeId: aws.String(fmt.Sprintf("vol-%d", n)),
+		}
+		for k, v := range testInstallationTags() {
+			volume.Tags = append(volume.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		o.Volumes = append(o.Volumes, volume)
+	}
+
+	return o, nil
+}
+
+func testSubnet(n int) *ec2.Subnet {
+	subnet := &ec2.Subnet{
+		AvailabilityZone:        aws.String(fmt.Sprintf("eu-central-1-%d", n)),
+		AvailabilityZoneId:      aws.String(fmt.Sprintf("euc1-az%d", n)),
+		AvailableIpAddressCount: aws.Int64(10),
+		CidrBlock:               aws.String("10.1.0.0/24"),
+		OwnerId:                 aws.String("000000000000"),
+		State:                   aws.String(ec2.SubnetStateAvailable),
+		SubnetId:                aws.String(fmt.Sprintf("subnet-%d", n)),
+		VpcId:                   aws.String(fmt.Sprintf("vpc-%d", testPagedItems-1)),
+	}
+	for k, v := range testInstallationTags() {
+		subnet.Tags = append(subnet.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	return subnet
+}
+
+type elbPagedMock struct {
+	elbiface.ELBAPI
+
+	t *testing.T
+}
+
+func (e *elbPagedMock) DescribeLoadBalancers(i *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	start, end, next := testPage(e.t, testPagedItems, i.Marker)
+
+	o := &elb.DescribeLoadBalancersOutput{NextMarker: next}
+	for n := start; n < end; n++ {
+		o.LoadBalancerDescriptions = append(o.LoadBalancerDescriptions, &elb.LoadBalancerDescription{
+			LoadBalancerName: aws.String(fmt.Sprintf("elb-%d", n)),
+		})
+	}
+
+	return o, nil
+}
+
+func (e *elbPagedMock) DescribeTags(i *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
+	o := &elb.DescribeTagsOutput{}
+	for _, name := range i.LoadBalancerNames {
+		d := &elb.TagDescription{LoadBalancerName: name}
+		for k, v := range testInstallationTags() {
+			d.Tags = append(d.Tags, &elb.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		o.TagDescriptions = append(o.TagDescriptions, d)
+	}
+
+	return o, nil
+}
+
+func (e *elbPagedMock) DescribeInstanceHealth(*elb.DescribeInstanceHealthInput) (*elb.DescribeInstanceHealthOutput, error) {
+	return &elb.DescribeInstanceHealthOutput{}, nil
+}
+
+// Test_pagination ensures that collectors follow all pages of the AWS API
+// responses and do not drop resources of large accounts.
+func Test_pagination(t *testing.T) {
+	testCases := []struct {
+		name    string
+		desc    *prometheus.Desc
+		collect func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error
+	}{
+		{
+			name: "case 0: cloudformation stacks",
+			desc: cloudFormationStackDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewCloudFormation(CloudFormationConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(ch, awsClients, "000000000000")
+			},
+		},
+		{
+			name: "case 1: ebs volumes",
+			desc: ebsVolumeDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewEBS(EBSConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(ch, awsClients, "000000000000")
+			},
+		},
+		{
+			name: "case 2: elbs",
+			desc: elbsDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewELB(ELBConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(context.Background(), ch, awsClients, "000000000000")
+			},
+		},
+		{
+			name: "case 3: nat gateways",
+			desc: natDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewNAT(NATConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(ch, awsClients, "000000000000")
+			},
+		},
+		{
+			name: "case 4: subnets",
+			desc: subnetsDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewSubnet(SubnetConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(context.Background(), ch, awsClients, "000000000000")
+			},
+		},
+		{
+			name: "case 5: vpcs",
+			desc: vpcsDesc,
+			collect: func(t *testing.T, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
+				c, err := NewVPC(VPCConfig{Helper: &helper{}, Logger: microloggertest.New(), InstallationName: "test"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c.collectForAccount(ch, awsClients, "000000000000")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsClients := clientaws.Clients{
+				CloudFormation: &cloudFormationPagedMock{t: t},
+				EC2:            &ec2PagedMock{t: t},
+				ELB:            &elbPagedMock{t: t},
+				Region:         "eu-central-1",
+			}
+
+			ch := make(chan prometheus.Metric, 100)
+			err := tc.collect(t, ch, awsClients)
+			if err != nil {
+				t.Fatal(err)
+			}
+			close(ch)
+
+			var count int
+			for m := range ch {
+				if m.Desc() == tc.desc {
+					count++
+				}
+			}
+
+			if count != testPagedItems {
+				t.Fatalf("expected %d metrics, got %d", testPagedItems, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/exporterkit/collector"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pollerMaxAgeIntervals is the number of intervals after which the metrics of
+// the latest successful refresh are dropped, so that a collector failing for a
+// longer time does not serve stale metrics forever.
+const pollerMaxAgeIntervals = 2
+
+type pollerConfig struct {
+	Collector collector.Interface
+	Logger    micrologger.Logger
+
+	Interval time.Duration
+	Name     string
+}
+
+// poller executes a collector in the background on its own interval and
+// keeps the metrics of its latest successful execution. Collect only serves
+// these metrics, so that scrapes do not have to wait for AWS API calls. The
+// metrics are served for at most pollerMaxAgeIntervals intervals.
+type poller struct {
+	collector collector.Interface
+	logger    micrologger.Logger
+
+	interval  time.Duration
+	metrics   []prometheus.Metric
+	mutex     sync.RWMutex
+	name      string
+	refreshed time.Time
+	// now is only meant to be replaced in tests.
+	now func() time.Time
+}
+
+func newPoller(config pollerConfig) (*poller, error) {
+	if config.Collector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Collector must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Interval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must be greater than 0", config)
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Name must not be empty", config)
+	}
+
+	p := &poller{
+		collector: config.Collector,
+		logger:    config.Logger,
+
+		interval: config.Interval,
+		metrics:  nil,
+		mutex:    sync.RWMutex{},
+		name:     config.Name,
+		now:      time.Now,
+	}
+
+	return p, nil
+}
+
+// Boot refreshes the metrics right away and then on every interval until the
+// given context is done.
+func (p *poller) Boot(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.refresh()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *poller) Collect(ch chan<- prometheus.Metric) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	// Metrics of collectors failing for longer than the maximum age are
+	// dropped, so that alerts notice the missing series instead of relying on
+	// stale values.
+	if p.now().Sub(p.refreshed) > pollerMaxAgeIntervals*p.interval {
+		return nil
+	}
+
+	for _, m := range p.metrics {
+		ch <- m
+	}
+
+	return nil
+}
+
+func (p *poller) Describe(ch chan<- *prometheus.Desc) error {
+	err := p.collector.Describe(ch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// refresh executes the underlying collector and replaces the served metrics
+// with the collected ones. When the collector fails, the metrics of the
+// previous refresh are kept until they exceed their maximum age.
+func (p *poller) refresh() {
+	var metrics []prometheus.Metric
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+
+	err := p.collector.Collect(ch)
+	close(ch)
+	<-done
+
+	if err != nil {
+		p.logger.Log("level", "error", "message", fmt.Sprintf("failed refreshing %s metrics", p.name), "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return
+	}
+
+	p.mutex.Lock()
+	p.metrics = metrics
+	p.refreshed = p.now()
+	p.mutex.Unlock()
+}
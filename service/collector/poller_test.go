@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var testDesc = prometheus.NewDesc("test_metric", "Test metric.", nil, nil)
+
+type collectorMock struct {
+	err   error
+	value float64
+}
+
+func (c *collectorMock) Collect(ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, c.value)
+	return c.err
+}
+
+func (c *collectorMock) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- testDesc
+	return nil
+}
+
+func collectValues(t *testing.T, p *poller) []float64 {
+	ch := make(chan prometheus.Metric, 10)
+
+	err := p.Collect(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var values []float64
+	for m := range ch {
+		values = append(values, readGauge(t, m))
+	}
+
+	return values
+}
+
+func Test_poller_refresh(t *testing.T) {
+	mock := &collectorMock{value: 1}
+
+	c := pollerConfig{
+		Collector: mock,
+		Logger:    microloggertest.New(),
+
+		Interval: time.Minute,
+		Name:     "test",
+	}
+
+	p, err := newPoller(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is served before the first refresh.
+	if values := collectValues(t, p); len(values) != 0 {
+		t.Fatalf("expected no metrics, got %v", values)
+	}
+
+	p.refresh()
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 1 {
+		t.Fatalf("expected [1], got %v", values)
+	}
+
+	// Serving does not execute the collector again.
+	mock.value = 2
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 1 {
+		t.Fatalf("expected [1], got %v", values)
+	}
+
+	// Failed refreshes keep serving the previous metrics.
+	mock.err = errors.New("test")
+	p.refresh()
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 1 {
+		t.Fatalf("expected [1], got %v", values)
+	}
+
+	mock.err = nil
+	p.refresh()
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 2 {
+		t.Fatalf("expected [2], got %v", values)
+	}
+}
+
+func Test_poller_maxAge(t *testing.T) {
+	mock := &collectorMock{value: 1}
+
+	c := pollerConfig{
+		Collector: mock,
+		Logger:    microloggertest.New(),
+
+		Interval: time.Minute,
+		Name:     "test",
+	}
+
+	p, err := newPoller(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	p.refresh()
+
+	// Failed refreshes keep serving the previous metrics up to their maximum
+	// age.
+	mock.err = errors.New("test")
+	now = now.Add(pollerMaxAgeIntervals * time.Minute)
+	p.refresh()
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 1 {
+		t.Fatalf("expected [1], got %v", values)
+	}
+
+	now = now.Add(time.Second)
+	if values := collectValues(t, p); len(values) != 0 {
+		t.Fatalf("expected no metrics, got %v", values)
+	}
+
+	// The next successful refresh serves metrics again.
+	mock.err = nil
+	mock.value = 2
+	p.refresh()
+	if values := collectValues(t, p); len(values) != 1 || values[0] != 2 {
+		t.Fatalf("expected [2], got %v", values)
+	}
+}
@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/aws-collector/service/controller/key"
+	"github.com/giantswarm/aws-collector/service/internal/cache"
+)
+
+const (
+	prefixRegionCacheKey = "regions/"
+)
+
+const (
+	// regionDiscoveryRetryInterval is the time the regions of an account are
+	// cached for when their discovery failed in the account or in some of its
+	// regions, so that it is retried soon without being retried on every
+	// scrape.
+	regionDiscoveryRetryInterval = 5 * time.Minute
+)
+
+type regionCache struct {
+	cache  *cache.StringCache
+	failed *cache.StringCache
+}
+
+func newRegionCache(expiration time.Duration) *regionCache {
+	retryInterval := regionDiscoveryRetryInterval
+	if expiration < retryInterval {
+		retryInterval = expiration
+	}
+
+	c := &regionCache{
+		cache:  cache.NewStringCache(expiration),
+		failed: cache.NewStringCache(retryInterval),
+	}
+
+	return c
+}
+
+func (r *regionCache) Get(arn string) ([]string, bool) {
+	raw, exists := r.cache.Get(getRegionCacheKey(arn))
+	if !exists {
+		raw, exists = r.failed.Get(getRegionCacheKey(arn))
+	}
+	if !exists {
+		return nil, false
+	}
+
+	return strings.Split(string(raw), ","), true
+}
+
+func (r *regionCache) Set(arn string, regions []string) {
+	r.cache.Set(getRegionCacheKey(arn), []byte(strings.Join(regions, ",")))
+}
+
+// SetFailed caches the regions of an account whose discovery failed for the
+// retry interval only.
+func (r *regionCache) SetFailed(arn string, regions []string) {
+	r.failed.Set(getRegionCacheKey(arn), []byte(strings.Join(regions, ",")))
+}
+
+func getRegionCacheKey(arn string) string {
+	return prefixRegionCacheKey + arn
+}
+
+// accountRegions returns the regions metrics are collected in for the given
+// role ARN. Without region discovery these are the configured regions. With
+// region discovery, every region enabled in the account which contains
+// resources of the installation is added. The discovered regions are cached.
+// If discovery fails the configured regions are used, and if it fails in some
+// regions only these are skipped. In both cases the result is only cached for
+// the retry interval.
+func (h *helper) accountRegions(arn string) []string {
+	if h.regionCache == nil {
+		return h.regions
+	}
+
+	regions, ok := h.regionCache.Get(arn)
+	if ok {
+		return regions
+	}
+
+	discovered, complete, err := h.discoverRegions(arn)
+	if err != nil {
+		h.logger.Log("level", "warning", "message", fmt.Sprintf("failed discovering regions of role ARN %#q, falling back to configured regions", arn), "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+		h.regionCache.SetFailed(arn, h.regions)
+		return h.regions
+	}
+
+	regions = append([]string{}, h.regions...)
+	for _, r := range discovered {
+		if !containsString(regions, r) {
+			regions = append(regions, r)
+		}
+	}
+
+	if complete {
+		h.regionCache.Set(arn, regions)
+	} else {
+		h.regionCache.SetFailed(arn, regions)
+	}
+
+	return regions
+}
+
+// discoverAccountRegions discovers the regions of the given role ARNs
+// concurrently, so that accountRegions is served from the cache afterwards.
+func (h *helper) discoverAccountRegions(arns []string) {
+	if h.regionCache == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, arn := range arns {
+		wg.Add(1)
+		go func(arn string) {
+			defer wg.Done()
+			h.accountRegions(arn)
+		}(arn)
+	}
+	wg.Wait()
+}
+
+// discoverRegions lists the regions enabled in the account of the given role
+// ARN and returns the ones containing EC2 resources tagged with the
+// installation name. The regions are checked concurrently. Regions which can
+// not be checked are logged and skipped, in which case the returned bool is
+// false.
+func (h *helper) discoverRegions(arn string) ([]string, bool, error) {
+	awsClients, err := h.clientPool.Get(arn, h.region)
+	if err != nil {
+		return nil, false, microerror.Mask(err)
+	}
+
+	i := &ec2.DescribeRegionsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("opt-in-status"),
+				Values: []*string{
+					aws.String("opt-in-not-required"),
+					aws.String("opted-in"),
+				},
+			},
+		},
+	}
+
+	o, err := awsClients.EC2.DescribeRegions(i)
+	if err != nil {
+		return nil, false, microerror.Mask(err)
+	}
+
+	used := make([]bool, len(o.Regions))
+	failed := make([]bool, len(o.Regions))
+
+	var wg sync.WaitGroup
+	for n, r := range o.Regions {
+		wg.Add(1)
+		go func(n int, region string) {
+			defer wg.Done()
+
+			ok, err := h.regionInUse(arn, region)
+			if err != nil {
+				h.logger.Log("level", "warning", "message", fmt.Sprintf("failed discovering region %s of role ARN %#q, skipping it", region, arn), "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+				failed[n] = true
+				return
+			}
+
+			used[n] = ok
+		}(n, aws.StringValue(r.RegionName))
+	}
+	wg.Wait()
+
+	complete := true
+	var regions []string
+	for n, r := range o.Regions {
+		if failed[n] {
+			complete = false
+		}
+		if used[n] {
+			regions = append(regions, aws.StringValue(r.RegionName))
+		}
+	}
+
+	return regions, complete, nil
+}
+
+// regionInUse returns whether the given region of the account of the given
+// role ARN contains EC2 resources tagged with the installation name.
+func (h *helper) regionInUse(arn string, region string) (bool, error) {
+	awsClients, err := h.clientPool.Get(arn, region)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	i := &ec2.DescribeTagsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key"),
+				Values: []*string{aws.String(key.TagInstallation)},
+			},
+			{
+				Name:   aws.String("value"),
+				Values: []*string{aws.String(h.installationName)},
+			},
+		},
+		// A single tagged resource is enough to know the region is in use.
+		MaxResults: aws.Int64(5),
+	}
+
+	o, err := awsClients.EC2.DescribeTags(i)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return len(o.Tags) > 0, nil
+}
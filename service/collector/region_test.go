@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+type ec2RegionMock struct {
+	ec2iface.EC2API
+
+	region string
+	tagged map[string]bool
+	// failing are the regions DescribeTags fails in.
+	failing map[string]bool
+	// regionsErr is returned by DescribeRegions if set.
+	regionsErr error
+
+	// describeRegionsCalls is shared by the mocks of all regions.
+	describeRegionsCalls *int
+	mutex                *sync.Mutex
+}
+
+func (e *ec2RegionMock) DescribeRegions(*ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	e.mutex.Lock()
+	*e.describeRegionsCalls++
+	e.mutex.Unlock()
+
+	if e.regionsErr != nil {
+		return nil, e.regionsErr
+	}
+
+	o := &ec2.DescribeRegionsOutput{
+		Regions: []*ec2.Region{
+			{RegionName: aws.String("ap-south-1")},
+			{RegionName: aws.String("eu-central-1")},
+			{RegionName: aws.String("eu-west-1")},
+			{RegionName: aws.String("us-east-1")},
+		},
+	}
+
+	return o, nil
+}
+
+func (e *ec2RegionMock) DescribeTags(*ec2.DescribeTagsInput) (*ec2.DescribeTagsOutput, error) {
+	if e.failing[e.region] {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	o := &ec2.DescribeTagsOutput{}
+	if e.tagged[e.region] {
+		o.Tags = []*ec2.TagDescription{
+			{ResourceId: aws.String("vpc-" + e.region)},
+		}
+	}
+
+	return o, nil
+}
+
+func newTestRegionDiscoveryHelper(t *testing.T, awsConfig clientaws.Config) *helper {
+	c := helperConfig{
+		K8sClient: fake.NewFakeClientWithScheme(newTestScheme(t)),
+		Logger:    microloggertest.New(),
+
+		AWSConfig:              awsConfig,
+		DiscoveryMaxAge:        time.Minute,
+		InstallationName:       "test",
+		RegionDiscoveryEnabled: true,
+		RegionDiscoveryTTL:     time.Hour,
+		Regions:                []string{"eu-west-1"},
+	}
+
+	h, err := newHelper(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func Test_helper_accountRegions_Discovery(t *testing.T) {
+	awsConfig := clientaws.Config{
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		Region:          "eu-central-1",
+	}
+
+	h := newTestRegionDiscoveryHelper(t, awsConfig)
+
+	var calls int
+	var mutex sync.Mutex
+	h.clientPool = newTestClientPool(t, awsConfig, func(region string) ec2iface.EC2API {
+		m := &ec2RegionMock{
+			region: region,
+			tagged: map[string]bool{
+				"us-east-1": true,
+			},
+
+			describeRegionsCalls: &calls,
+			mutex:                &mutex,
+		}
+
+		return m
+	})
+
+	// Configured regions come first, discovered regions containing resources
+	// of the installation are added. Untagged regions are skipped.
+	expected := []string{"eu-central-1", "eu-west-1", "us-east-1"}
+
+	for i := 0; i < 2; i++ {
+		regions := h.accountRegions("")
+		if !cmp.Equal(regions, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, regions))
+		}
+	}
+
+	// Discovered regions are cached.
+	if calls != 1 {
+		t.Fatalf("expected 1 DescribeRegions call, got %d", calls)
+	}
+}
+
+func Test_helper_accountRegions_Failures(t *testing.T) {
+	awsConfig := clientaws.Config{
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		Region:          "eu-central-1",
+	}
+
+	testCases := []struct {
+		name       string
+		failing    map[string]bool
+		regionsErr error
+		expected   []string
+	}{
+		{
+			name: "case 0: regions which can not be checked are skipped",
+			failing: map[string]bool{
+				"ap-south-1": true,
+			},
+			expected: []string{"eu-central-1", "eu-west-1", "us-east-1"},
+		},
+		{
+			name:       "case 1: configured regions are used if regions can not be listed",
+			regionsErr: fmt.Errorf("unauthorized"),
+			expected:   []string{"eu-central-1", "eu-west-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestRegionDiscoveryHelper(t, awsConfig)
+
+			var calls int
+			var mutex sync.Mutex
+			h.clientPool = newTestClientPool(t, awsConfig, func(region string) ec2iface.EC2API {
+				m := &ec2RegionMock{
+					region: region,
+					tagged: map[string]bool{
+						"us-east-1": true,
+					},
+					failing:    tc.failing,
+					regionsErr: tc.regionsErr,
+
+					describeRegionsCalls: &calls,
+					mutex:                &mutex,
+				}
+
+				return m
+			})
+
+			for i := 0; i < 2; i++ {
+				regions := h.accountRegions("")
+				if !cmp.Equal(regions, tc.expected) {
+					t.Fatalf("\n\n%s\n", cmp.Diff(tc.expected, regions))
+				}
+			}
+
+			// Failed discoveries are cached as well.
+			if calls != 1 {
+				t.Fatalf("expected 1 DescribeRegions call, got %d", calls)
+			}
+		})
+	}
+}
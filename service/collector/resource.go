@@ -0,0 +1,418 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/giantswarm/microerror"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+	"github.com/giantswarm/aws-collector/service/internal/cache"
+)
+
+const (
+	// __ResourceCache__ is used as temporal cache key to save the tags of the
+	// managed resources.
+	prefixResourceCacheKey = "__ResourceCache__"
+)
+
+// resourceTagsFunc returns the tags of every resource of one type in the
+// account and region of the given clients, regardless of the installation
+// they belong to.
+type resourceTagsFunc func(awsClients clientaws.Clients) ([]map[string]string, error)
+
+// managedResourceTypes maps the types of the resources created for clusters to
+// the function listing their tags.
+var managedResourceTypes = map[string]resourceTagsFunc{
+	"cloudformation_stack": listCloudFormationStackTags,
+	"ebs_volume":           listEBSVolumeTags,
+	"ec2_instance":         listEC2InstanceTags,
+	"eip":                  listEIPTags,
+	"elb":                  listELBTags,
+	"elbv2":                listELBv2Tags,
+	"nat_gateway":          listNATGatewayTags,
+	"subnet":               listSubnetTags,
+	"vpc":                  listVPCTags,
+}
+
+// resourceCache holds the tags of the managed resources per account, region
+// and resource type. It is shared by all collectors checking the managed
+// resources, so that every resource is only listed once per expiration.
+type resourceCache struct {
+	cache *cache.StringCache
+}
+
+func newResourceCache(expiration time.Duration) *resourceCache {
+	cache := &resourceCache{
+		cache: cache.NewStringCache(expiration),
+	}
+
+	return cache
+}
+
+// List returns the tags of every resource of the given type from the cache, or
+// lists them if they are not cached. Empty listings are cached as well.
+func (r *resourceCache) List(awsClients clientaws.Clients, accountID string, resourceType string) ([]map[string]string, error) {
+	k := getResourceCacheKey(accountID, awsClients.Region, resourceType)
+
+	raw, exists := r.cache.Get(k)
+	if exists {
+		var resources []map[string]string
+		err := json.Unmarshal(raw, &resources)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return resources, nil
+	}
+
+	resourceTags, ok := managedResourceTypes[resourceType]
+	if !ok {
+		return nil, microerror.Maskf(notFoundError, "resource type %#q", resourceType)
+	}
+
+	resources, err := resourceTags(awsClients)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	contentSerialized, err := json.Marshal(resources)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.cache.Set(k, contentSerialized)
+
+	return resources, nil
+}
+
+func getResourceCacheKey(accountID string, region string, resourceType string) string {
+	return prefixResourceCacheKey + accountID + "/" + region + "/" + resourceType
+}
+
+func listCloudFormationStackTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	// Deleted stacks are not listed without stack name.
+	i := &cloudformation.DescribeStacksInput{}
+	for {
+		o, err := awsClients.CloudFormation.DescribeStacks(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, stack := range o.Stacks {
+			tags := map[string]string{}
+			for _, tag := range stack.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+
+			res = append(res, tags)
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+func listEBSVolumeTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	i := &ec2.DescribeVolumesInput{
+		MaxResults: aws.Int64(500),
+	}
+	for {
+		o, err := awsClients.EC2.DescribeVolumes(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, v := range o.Volumes {
+			res = append(res, ec2TagMap(v.Tags))
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+func listEC2InstanceTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	i := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			// Terminated instances are still listed for a while.
+			{
+				Name: aws.String("instance-state-name"),
+				Values: []*string{
+					aws.String(ec2.InstanceStateNamePending),
+					aws.String(ec2.InstanceStateNameRunning),
+					aws.String(ec2.InstanceStateNameShuttingDown),
+					aws.String(ec2.InstanceStateNameStopping),
+					aws.String(ec2.InstanceStateNameStopped),
+				},
+			},
+		},
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		o, err := awsClients.EC2.DescribeInstances(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, reservation := range o.Reservations {
+			for _, instance := range reservation.Instances {
+				res = append(res, ec2TagMap(instance.Tags))
+			}
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+func listEIPTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	// DescribeAddresses is not paginated and returns all addresses at once.
+	i := &ec2.DescribeAddressesInput{}
+	o, err := awsClients.EC2.DescribeAddresses(i)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, a := range o.Addresses {
+		res = append(res, ec2TagMap(a.Tags))
+	}
+
+	return res, nil
+}
+
+func listELBTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var names []*string
+	{
+		i := &elb.DescribeLoadBalancersInput{}
+		for {
+			o, err := awsClients.ELB.DescribeLoadBalancers(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			for _, d := range o.LoadBalancerDescriptions {
+				names = append(names, d.LoadBalancerName)
+			}
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
+		}
+	}
+
+	var res []map[string]string
+
+	// Tags can only be described for a limited number of load balancers at
+	// once.
+	for start := 0; start < len(names); start += maxELBsInOneDescribeTagsBatch {
+		end := start + maxELBsInOneDescribeTagsBatch
+		if end > len(names) {
+			end = len(names)
+		}
+
+		i := &elb.DescribeTagsInput{
+			LoadBalancerNames: names[start:end],
+		}
+		o, err := awsClients.ELB.DescribeTags(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range o.TagDescriptions {
+			tags := map[string]string{}
+			for _, tag := range d.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+
+			res = append(res, tags)
+		}
+	}
+
+	return res, nil
+}
+
+func listELBv2Tags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var arns []*string
+	{
+		i := &elbv2.DescribeLoadBalancersInput{}
+		for {
+			o, err := awsClients.ELBv2.DescribeLoadBalancers(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			for _, lb := range o.LoadBalancers {
+				arns = append(arns, lb.LoadBalancerArn)
+			}
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
+		}
+	}
+
+	var res []map[string]string
+
+	// Tags can only be described for a limited number of load balancers at
+	// once.
+	for start := 0; start < len(arns); start += maxELBv2sInOneDescribeTagsBatch {
+		end := start + maxELBv2sInOneDescribeTagsBatch
+		if end > len(arns) {
+			end = len(arns)
+		}
+
+		i := &elbv2.DescribeTagsInput{
+			ResourceArns: arns[start:end],
+		}
+		o, err := awsClients.ELBv2.DescribeTags(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range o.TagDescriptions {
+			tags := map[string]string{}
+			for _, tag := range d.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+
+			res = append(res, tags)
+		}
+	}
+
+	return res, nil
+}
+
+func listNATGatewayTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	i := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			// Deleted NAT gateways are still listed for a while.
+			{
+				Name: aws.String("state"),
+				Values: []*string{
+					aws.String(ec2.NatGatewayStateAvailable),
+					aws.String(ec2.NatGatewayStatePending),
+				},
+			},
+		},
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		o, err := awsClients.EC2.DescribeNatGateways(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, n := range o.NatGateways {
+			res = append(res, ec2TagMap(n.Tags))
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+func listSubnetTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	i := &ec2.DescribeSubnetsInput{}
+	for {
+		o, err := awsClients.EC2.DescribeSubnets(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, s := range o.Subnets {
+			res = append(res, ec2TagMap(s.Tags))
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+func listVPCTags(awsClients clientaws.Clients) ([]map[string]string, error) {
+	var res []map[string]string
+
+	i := &ec2.DescribeVpcsInput{}
+	for {
+		o, err := awsClients.EC2.DescribeVpcs(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, v := range o.Vpcs {
+			res = append(res, ec2TagMap(v.Tags))
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return res, nil
+}
+
+// installationFilters returns the EC2 filters matching the resources tagged
+// with the given installation. No filters are returned without installation.
+func installationFilters(installation string) []*ec2.Filter {
+	if installation == "" {
+		return nil
+	}
+
+	return []*ec2.Filter{
+		{
+			Name: aws.String(fmt.Sprintf("tag:%s", key.TagInstallation)),
+			Values: []*string{
+				aws.String(installation),
+			},
+		},
+	}
+}
+
+func ec2TagMap(tags []*ec2.Tag) map[string]string {
+	m := map[string]string{}
+	for _, tag := range tags {
+		m[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return m
+}
@@ -1,21 +1,30 @@
 package collector
 
 import (
-	"context"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/servicequotas"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/internal/cache"
 )
 
 const (
-	labelServiceQuota = "service_quota"
+	labelKind      = "kind"
+	labelQuotaCode = "quota_code"
+	labelQuotaName = "quota_name"
+)
+
+const (
+	// kindApplied is the kind of the quota value currently applied to an
+	// account, which differs from the default value once it got increased.
+	kindApplied = "applied"
+	// kindDefault is the kind of the AWS default quota value.
+	kindDefault = "default"
 )
 
 const (
@@ -28,28 +37,94 @@ var (
 		"Service Quota information.",
 		[]string{
 			labelAccountID,
-			labelServiceQuota,
+			labelService,
+			labelQuotaCode,
+			labelQuotaName,
+			labelKind,
+			labelRegion,
+		},
+		nil,
+	)
+	serviceQuotaUsageDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemServiceQuota, "usage"),
+		"Current usage of a service quota.",
+		[]string{
+			labelAccountID,
+			labelService,
+			labelQuotaCode,
+			labelQuotaName,
+			labelRegion,
+		},
+		nil,
+	)
+	serviceQuotaUtilizationRatioDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemServiceQuota, "utilization_ratio"),
+		"Current usage of a service quota divided by its applied value.",
+		[]string{
+			labelAccountID,
+			labelService,
+			labelQuotaCode,
+			labelQuotaName,
+			labelRegion,
 		},
 		nil,
 	)
-	NATQuotaCode   = "L-FE5A380F"
-	NATQuotaName   = "nat-gateway"
-	VPCServiceCode = "vpc"
 )
 
+// ServiceQuotaSpec identifies a service quota collected by the ServiceQuota
+// collector.
+type ServiceQuotaSpec struct {
+	// ServiceCode is the code of the AWS service the quota belongs to, e.g.
+	// vpc.
+	ServiceCode string
+	// QuotaCode is the code of the quota, e.g. L-FE5A380F.
+	QuotaCode string
+	// Name is the friendly name the quota is reported with, e.g. nat-gateway.
+	Name string
+}
+
+// DefaultServiceQuotas are the service quotas collected when no quotas are
+// configured.
+var DefaultServiceQuotas = []ServiceQuotaSpec{
+	{ServiceCode: "autoscaling", QuotaCode: "L-CDE20ADC", Name: "auto-scaling-groups-per-region"},
+	{ServiceCode: "cloudformation", QuotaCode: "L-0485CFF4", Name: "cloudformation-stacks"},
+	{ServiceCode: "ec2", QuotaCode: "L-0263D0A3", Name: "elastic-ips"},
+	{ServiceCode: "ec2", QuotaCode: "L-1216C47A", Name: "on-demand-standard-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-1945791B", Name: "on-demand-inf-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-2C3B7624", Name: "on-demand-trn-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-417A185B", Name: "on-demand-p-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-43DA4232", Name: "on-demand-high-memory-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-6E869C2A", Name: "on-demand-dl-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-7295265B", Name: "on-demand-x-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-74FC7D96", Name: "on-demand-f-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-DB2E81BA", Name: "on-demand-g-and-vt-vcpus"},
+	{ServiceCode: "ec2", QuotaCode: "L-F7808C92", Name: "on-demand-hpc-vcpus"},
+	{ServiceCode: "elasticloadbalancing", QuotaCode: "L-53DA6B97", Name: "application-load-balancers-per-region"},
+	{ServiceCode: "elasticloadbalancing", QuotaCode: "L-69A177A2", Name: "network-load-balancers-per-region"},
+	{ServiceCode: "elasticloadbalancing", QuotaCode: "L-E9E9831D", Name: "classic-load-balancers-per-region"},
+	{ServiceCode: "vpc", QuotaCode: "L-DF5E4CA3", Name: "network-interfaces-per-region"},
+	{ServiceCode: "vpc", QuotaCode: "L-F678F1CE", Name: "vpcs-per-region"},
+	{ServiceCode: "vpc", QuotaCode: "L-FE5A380F", Name: "nat-gateway"},
+}
+
 type ServiceQuotaConfig struct {
 	Helper *helper
 	Logger micrologger.Logger
 
 	InstallationName string
+	// Quotas are the service quotas to collect. DefaultServiceQuotas are used
+	// when empty.
+	Quotas []ServiceQuotaSpec
 }
 
 type ServiceQuota struct {
 	awsAPIcache *cache.Float64Cache
 	helper      *helper
 	logger      micrologger.Logger
+	usageCache  *cache.Float64Cache
 
 	installationName string
+	quotas           []ServiceQuotaSpec
 }
 
 func NewServiceQuota(config ServiceQuotaConfig) (*ServiceQuota, error) {
@@ -63,118 +138,260 @@ func NewServiceQuota(config ServiceQuotaConfig) (*ServiceQuota, error) {
 	if config.InstallationName == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
 	}
+	for _, q := range config.Quotas {
+		if q.ServiceCode == "" || q.QuotaCode == "" || q.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Quotas must only contain quotas with service code, quota code and name, got %#v", config, q)
+		}
+	}
+
+	quotas := config.Quotas
+	if len(quotas) == 0 {
+		quotas = DefaultServiceQuotas
+	}
 
 	v := &ServiceQuota{
-		// Default quotas are changed by request to AWS support and they are
+		// Quotas are changed by request to AWS support and they are
 		// considered quite static information, then 12 hours for the cache
 		// expiration is a reasonable value.
 		awsAPIcache: cache.NewFloat64Cache(time.Minute * 720),
 		helper:      config.Helper,
 		logger:      config.Logger,
+		// Usages change with the resources of the account, so they are only
+		// cached to not list the resources on every scrape.
+		usageCache: cache.NewFloat64Cache(time.Minute * 5),
 
 		installationName: config.InstallationName,
+		quotas:           quotas,
 	}
 
 	return v, nil
 }
 
 func (v *ServiceQuota) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := v.helper.ListReconciledClusters()
+	err := v.helper.CollectForAccounts(ch, subsystemServiceQuota, v.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	awsClientsList, err := v.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	return nil
+}
 
-	var g errgroup.Group
+func (v *ServiceQuota) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- serviceQuotaDesc
+	ch <- serviceQuotaUsageDesc
+	ch <- serviceQuotaUtilizationRatioDesc
+	return nil
+}
+
+func (v *ServiceQuota) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	for _, q := range v.quotas {
+		defaultValue, appliedValue, ok, err := v.getQuota(q, awsClients, accountID)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !ok {
+			// Some regions do not support ServiceQuota API.
+			continue
+		}
 
-	for _, item := range awsClientsList {
-		awsClients := item
+		ch <- prometheus.MustNewConstMetric(
+			serviceQuotaDesc,
+			prometheus.GaugeValue,
+			defaultValue,
+			accountID,
+			q.ServiceCode,
+			q.QuotaCode,
+			q.Name,
+			kindDefault,
+			awsClients.Region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			serviceQuotaDesc,
+			prometheus.GaugeValue,
+			appliedValue,
+			accountID,
+			q.ServiceCode,
+			q.QuotaCode,
+			q.Name,
+			kindApplied,
+			awsClients.Region,
+		)
 
-		g.Go(func() error {
-			err := v.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		usage, ok, err := v.getUsage(q, awsClients, accountID)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !ok {
+			continue
+		}
 
-			return nil
-		})
-	}
+		ch <- prometheus.MustNewConstMetric(
+			serviceQuotaUsageDesc,
+			prometheus.GaugeValue,
+			usage,
+			accountID,
+			q.ServiceCode,
+			q.QuotaCode,
+			q.Name,
+			awsClients.Region,
+		)
 
-	err = g.Wait()
-	if err != nil {
-		return microerror.Mask(err)
+		if appliedValue > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				serviceQuotaUtilizationRatioDesc,
+				prometheus.GaugeValue,
+				usage/appliedValue,
+				accountID,
+				q.ServiceCode,
+				q.QuotaCode,
+				q.Name,
+				awsClients.Region,
+			)
+		}
 	}
 
 	return nil
 }
 
-func (v *ServiceQuota) Describe(ch chan<- *prometheus.Desc) error {
-	ch <- serviceQuotaDesc
-	return nil
-}
+// getQuota returns the default and the applied value of the given quota in
+// the account and region of the given clients. Both values are cached per
+// account, region and quota, since quotas can be increased for single
+// accounts and regions. The returned bool is false if the ServiceQuota API is
+// not available in the region of the given clients.
+func (v *ServiceQuota) getQuota(q ServiceQuotaSpec, awsClients clientaws.Clients, accountID string) (float64, float64, bool, error) {
+	defaultKey := getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, q.QuotaCode, kindDefault)
+	appliedKey := getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, q.QuotaCode, kindApplied)
+
+	defaultValue, ok := v.awsAPIcache.Get(defaultKey)
+	if !ok {
+		i := &servicequotas.GetAWSDefaultServiceQuotaInput{
+			QuotaCode:   aws.String(q.QuotaCode),
+			ServiceCode: aws.String(q.ServiceCode),
+		}
+
+		o, err := awsClients.ServiceQuotas.GetAWSDefaultServiceQuota(i)
+		if IsEndpointNotAvailable(err) {
+			return 0, 0, false, nil
+		} else if err != nil {
+			return 0, 0, false, microerror.Mask(err)
+		}
+
+		defaultValue = aws.Float64Value(o.Quota.Value)
+		v.awsAPIcache.Set(defaultKey, defaultValue)
+	}
+
+	appliedValue, ok := v.awsAPIcache.Get(appliedKey)
+	if ok {
+		return defaultValue, appliedValue, true, nil
+	}
+
+	// Quotas which were never changed for the account are not necessarily
+	// listed, in which case the default value applies. The service is only
+	// listed again once the applied values of its listing expired.
+	listedKey := getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, "", "listed")
+	if _, ok := v.awsAPIcache.Get(listedKey); ok {
+		return defaultValue, defaultValue, true, nil
+	}
 
-func (v *ServiceQuota) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	accountID, err := v.helper.AWSAccountID(awsClients)
+	values, err := listAppliedQuotas(q.ServiceCode, awsClients)
 	if err != nil {
-		return microerror.Mask(err)
+		return 0, 0, false, microerror.Mask(err)
 	}
 
-	// natQuotaValue reflects the value of number of NAT Gateways that can be
-	// created by the operator in a specific VPC for each availability zone.
-	var natQuotaValue float64
-	if val, ok := v.awsAPIcache.Get(NATQuotaCode); ok {
-		natQuotaValue = val
-	} else {
-		natQuotaValue, err := getDefaultVPCQuotaFor(NATQuotaCode, awsClients)
-		if err != nil {
-			return microerror.Mask(err)
-		}
-		v.awsAPIcache.Set(NATQuotaCode, natQuotaValue)
+	// All quotas of the service are cached, so that the service does not have
+	// to be listed again for its other quotas. The listing is marked first, so
+	// that it never outlives the applied values.
+	v.awsAPIcache.Set(listedKey, 1)
+	for code, val := range values {
+		v.awsAPIcache.Set(getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, code, kindApplied), val)
 	}
 
-	ch <- prometheus.MustNewConstMetric(
-		serviceQuotaDesc,
-		prometheus.GaugeValue,
-		natQuotaValue,
-		accountID,
-		NATQuotaName,
-	)
+	appliedValue, ok = values[q.QuotaCode]
+	if !ok {
+		appliedValue = defaultValue
+	}
 
-	return nil
+	return defaultValue, appliedValue, true, nil
 }
 
-func getDefaultVPCQuotaFor(quotaCode string, awsClients clientaws.Clients) (float64, error) {
-	id := &servicequotas.GetAWSDefaultServiceQuotaInput{
-		QuotaCode:   &NATQuotaCode,
-		ServiceCode: &VPCServiceCode,
+// getUsage returns the usage of the given quota in the account and region of
+// the given clients. Usages are cached for a short time, so that the resources
+// counted by the usage functions are not listed on every scrape. The returned
+// bool is false if the usage of the quota is not known.
+func (v *ServiceQuota) getUsage(q ServiceQuotaSpec, awsClients clientaws.Clients, accountID string) (float64, bool, error) {
+	usageKey := getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, q.QuotaCode, "usage")
+
+	usage, ok := v.usageCache.Get(usageKey)
+	if ok {
+		return usage, true, nil
 	}
-	//Get the default NAT quota for the specific account
-	od, err := awsClients.ServiceQuotas.GetAWSDefaultServiceQuota(id)
-	if IsEndpointNotAvailable(err) {
-		// Some regions do not support ServiceQuota API.
-		return 0, nil
-	} else if err != nil {
-		return 0, microerror.Mask(err)
+
+	if usageFunc, ok := quotaUsageFuncs[q.QuotaCode]; ok {
+		usage, err := usageFunc(awsClients)
+		if err != nil {
+			return 0, false, microerror.Mask(err)
+		}
+
+		v.usageCache.Set(usageKey, usage)
+
+		return usage, true, nil
 	}
-	natQuotaValue := *od.Quota.Value
 
-	il := &servicequotas.ListServiceQuotasInput{
-		ServiceCode: &VPCServiceCode,
+	if !isOnDemandVCPUQuota(q.QuotaCode) {
+		return 0, false, nil
 	}
-	//Get the NAT quota in case it has been modified by AWS support request
-	ol, err := awsClients.ServiceQuotas.ListServiceQuotas(il)
+
+	// The usages of all On-Demand vCPU quotas are computed and cached at once,
+	// so that the instances are only listed once.
+	usages, err := onDemandVCPUUsages(awsClients)
 	if err != nil {
-		return natQuotaValue, microerror.Mask(err)
+		return 0, false, microerror.Mask(err)
+	}
+
+	for quotaCode, u := range usages {
+		v.usageCache.Set(getServiceQuotaCacheKey(accountID, awsClients.Region, q.ServiceCode, quotaCode, "usage"), u)
+	}
+
+	return usages[q.QuotaCode], true, nil
+}
+
+func isOnDemandVCPUQuota(quotaCode string) bool {
+	for _, code := range onDemandVCPUQuotas {
+		if code == quotaCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listAppliedQuotas returns the quota values applied to the account of the
+// given clients for the given service, keyed by quota code.
+func listAppliedQuotas(serviceCode string, awsClients clientaws.Clients) (map[string]float64, error) {
+	values := map[string]float64{}
+
+	i := &servicequotas.ListServiceQuotasInput{
+		ServiceCode: aws.String(serviceCode),
 	}
-	for _, sq := range ol.Quotas {
-		if *sq.QuotaCode == NATQuotaCode {
-			natQuotaValue = *sq.Value
+	for {
+		o, err := awsClients.ServiceQuotas.ListServiceQuotas(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
 		}
+
+		for _, sq := range o.Quotas {
+			values[aws.StringValue(sq.QuotaCode)] = aws.Float64Value(sq.Value)
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
 	}
 
-	return natQuotaValue, nil
+	return values, nil
+}
+
+func getServiceQuotaCacheKey(accountID string, region string, serviceCode string, quotaCode string, kind string) string {
+	return accountID + "/" + region + "/" + serviceCode + "/" + quotaCode + "/" + kind
 }
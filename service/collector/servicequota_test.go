@@ -0,0 +1,233 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+type serviceQuotasMock struct {
+	servicequotasiface.ServiceQuotasAPI
+
+	// defaults are the default values keyed by quota code.
+	defaults map[string]float64
+	// pages are the pages of applied quotas returned by ListServiceQuotas.
+	pages [][]*servicequotas.ServiceQuota
+
+	listCalls int
+}
+
+func (s *serviceQuotasMock) GetAWSDefaultServiceQuota(i *servicequotas.GetAWSDefaultServiceQuotaInput) (*servicequotas.GetAWSDefaultServiceQuotaOutput, error) {
+	o := &servicequotas.GetAWSDefaultServiceQuotaOutput{
+		Quota: &servicequotas.ServiceQuota{
+			QuotaCode: i.QuotaCode,
+			Value:     aws.Float64(s.defaults[*i.QuotaCode]),
+		},
+	}
+
+	return o, nil
+}
+
+func (s *serviceQuotasMock) ListServiceQuotas(i *servicequotas.ListServiceQuotasInput) (*servicequotas.ListServiceQuotasOutput, error) {
+	s.listCalls++
+
+	var page int
+	if i.NextToken != nil {
+		var err error
+		page, err = strconv.Atoi(*i.NextToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	o := &servicequotas.ListServiceQuotasOutput{}
+	if page < len(s.pages) {
+		o.Quotas = s.pages[page]
+	}
+	if page+1 < len(s.pages) {
+		o.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+
+	return o, nil
+}
+
+func newTestServiceQuota(t *testing.T, quotas []ServiceQuotaSpec) *ServiceQuota {
+	c := ServiceQuotaConfig{
+		Helper: newTestHelper(t, fake.NewFakeClientWithScheme(newTestScheme(t))),
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+		Quotas:           quotas,
+	}
+
+	s, err := NewServiceQuota(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func appliedQuota(code string, value float64) *servicequotas.ServiceQuota {
+	return &servicequotas.ServiceQuota{
+		QuotaCode: aws.String(code),
+		Value:     aws.Float64(value),
+	}
+}
+
+// collectServiceQuotas returns the collected quota values formatted as
+// <account>/<quota code>/<kind>=<value>.
+func collectServiceQuotas(t *testing.T, s *ServiceQuota, awsClients clientaws.Clients, accountID string) []string {
+	ch := make(chan prometheus.Metric, 100)
+
+	err := s.collectForAccount(ch, awsClients, accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var values []string
+	for m := range ch {
+		if m.Desc() != serviceQuotaDesc {
+			continue
+		}
+
+		var pb dto.Metric
+		err := m.Write(&pb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		labels := map[string]string{}
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		values = append(values, fmt.Sprintf("%s/%s/%s=%v", labels[labelAccountID], labels[labelQuotaCode], labels[labelKind], pb.Gauge.GetValue()))
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+func Test_ServiceQuota_collectForAccount(t *testing.T) {
+	// The quotas have no usage function, so that no other AWS APIs are
+	// called.
+	quotas := []ServiceQuotaSpec{
+		{ServiceCode: "test", QuotaCode: "L-00000001", Name: "first"},
+		{ServiceCode: "test", QuotaCode: "L-00000002", Name: "second"},
+	}
+
+	testCases := []struct {
+		name     string
+		accounts map[string]*serviceQuotasMock
+		expected []string
+	}{
+		{
+			name: "case 0: applied values are found on every page",
+			accounts: map[string]*serviceQuotasMock{
+				"111111111111": {
+					defaults: map[string]float64{"L-00000001": 5, "L-00000002": 5},
+					pages: [][]*servicequotas.ServiceQuota{
+						{appliedQuota("L-00000001", 10)},
+						{appliedQuota("L-00000002", 20)},
+					},
+				},
+			},
+			expected: []string{
+				"111111111111/L-00000001/applied=10",
+				"111111111111/L-00000001/default=5",
+				"111111111111/L-00000002/applied=20",
+				"111111111111/L-00000002/default=5",
+			},
+		},
+		{
+			name: "case 1: unlisted quotas fall back to their default value",
+			accounts: map[string]*serviceQuotasMock{
+				"111111111111": {
+					defaults: map[string]float64{"L-00000001": 5, "L-00000002": 5},
+					pages: [][]*servicequotas.ServiceQuota{
+						{appliedQuota("L-00000001", 10)},
+					},
+				},
+			},
+			expected: []string{
+				"111111111111/L-00000001/applied=10",
+				"111111111111/L-00000001/default=5",
+				"111111111111/L-00000002/applied=5",
+				"111111111111/L-00000002/default=5",
+			},
+		},
+		{
+			name: "case 2: every account reports its own values",
+			accounts: map[string]*serviceQuotasMock{
+				"111111111111": {
+					defaults: map[string]float64{"L-00000001": 5, "L-00000002": 5},
+					pages: [][]*servicequotas.ServiceQuota{
+						{appliedQuota("L-00000001", 10), appliedQuota("L-00000002", 20)},
+					},
+				},
+				"222222222222": {
+					defaults: map[string]float64{"L-00000001": 5, "L-00000002": 5},
+					pages: [][]*servicequotas.ServiceQuota{
+						{appliedQuota("L-00000001", 30), appliedQuota("L-00000002", 40)},
+					},
+				},
+			},
+			expected: []string{
+				"111111111111/L-00000001/applied=10",
+				"111111111111/L-00000001/default=5",
+				"111111111111/L-00000002/applied=20",
+				"111111111111/L-00000002/default=5",
+				"222222222222/L-00000001/applied=30",
+				"222222222222/L-00000001/default=5",
+				"222222222222/L-00000002/applied=40",
+				"222222222222/L-00000002/default=5",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServiceQuota(t, quotas)
+
+			// Every account is collected twice, so that the second collection
+			// is served from the cache.
+			var values []string
+			for i := 0; i < 2; i++ {
+				values = nil
+				for accountID, mock := range tc.accounts {
+					awsClients := clientaws.Clients{
+						Region:        "eu-central-1",
+						ServiceQuotas: mock,
+					}
+
+					values = append(values, collectServiceQuotas(t, s, awsClients, accountID)...)
+				}
+				sort.Strings(values)
+
+				if !cmp.Equal(values, tc.expected) {
+					t.Fatalf("\n\n%s\n", cmp.Diff(tc.expected, values))
+				}
+			}
+
+			for accountID, mock := range tc.accounts {
+				if mock.listCalls != len(mock.pages) {
+					t.Fatalf("expected %d ListServiceQuotas calls for account %s, got %d", len(mock.pages), accountID, mock.listCalls)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package collector
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/giantswarm/microerror"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+// onDemandVCPUQuotas maps the instance families to the code of the Running
+// On-Demand instances quota their vCPUs are counted against. Instance families
+// are identified by the letters their instance types start with, e.g. m for
+// m5.xlarge or inf for inf1.xlarge. Mac instances are not listed, since they
+// run on Dedicated Hosts whose quotas count hosts instead of vCPUs.
+var onDemandVCPUQuotas = map[string]string{
+	// Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances.
+	"a":  "L-1216C47A",
+	"c":  "L-1216C47A",
+	"d":  "L-1216C47A",
+	"h":  "L-1216C47A",
+	"i":  "L-1216C47A",
+	"im": "L-1216C47A",
+	"is": "L-1216C47A",
+	"m":  "L-1216C47A",
+	"r":  "L-1216C47A",
+	"t":  "L-1216C47A",
+	"z":  "L-1216C47A",
+	// Running On-Demand DL instances.
+	"dl": "L-6E869C2A",
+	// Running On-Demand F instances.
+	"f": "L-74FC7D96",
+	// Running On-Demand G and VT instances.
+	"g":  "L-DB2E81BA",
+	"vt": "L-DB2E81BA",
+	// Running On-Demand HPC instances.
+	"hpc": "L-F7808C92",
+	// Running On-Demand High Memory instances.
+	"u": "L-43DA4232",
+	// Running On-Demand Inf instances.
+	"inf": "L-1945791B",
+	// Running On-Demand P instances.
+	"p": "L-417A185B",
+	// Running On-Demand Trn instances.
+	"trn": "L-2C3B7624",
+	// Running On-Demand X instances.
+	"x": "L-7295265B",
+}
+
+// quotaUsageFunc returns the current usage of a service quota in the account
+// and region of the given clients.
+type quotaUsageFunc func(awsClients clientaws.Clients) (float64, error)
+
+// quotaUsageFuncs maps quota codes to the function computing their usage.
+// Quotas without usage function are only reported with their limits, unless
+// they are On-Demand vCPU quotas, whose usages are computed together by
+// onDemandVCPUUsages.
+var quotaUsageFuncs = map[string]quotaUsageFunc{
+	"L-0263D0A3": elasticIPUsage,
+	"L-0485CFF4": cloudFormationStackUsage,
+	"L-53DA6B97": loadBalancerUsage(elbv2.LoadBalancerTypeEnumApplication),
+	"L-69A177A2": loadBalancerUsage(elbv2.LoadBalancerTypeEnumNetwork),
+	"L-CDE20ADC": autoScalingGroupUsage,
+	"L-DF5E4CA3": networkInterfaceUsage,
+	"L-E9E9831D": classicLoadBalancerUsage,
+	"L-F678F1CE": vpcUsage,
+	"L-FE5A380F": natGatewayPerAZUsage,
+}
+
+func autoScalingGroupUsage(awsClients clientaws.Clients) (float64, error) {
+	var usage float64
+
+	var nextToken *string
+	for {
+		i := &autoscaling.DescribeAutoScalingGroupsInput{
+			NextToken: nextToken,
+		}
+		o, err := awsClients.AutoScaling.DescribeAutoScalingGroups(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		usage += float64(len(o.AutoScalingGroups))
+
+		nextToken = o.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+
+	return usage, nil
+}
+
+func classicLoadBalancerUsage(awsClients clientaws.Clients) (float64, error) {
+	var usage float64
+
+	var marker *string
+	for {
+		i := &elb.DescribeLoadBalancersInput{
+			Marker: marker,
+		}
+		o, err := awsClients.ELB.DescribeLoadBalancers(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		usage += float64(len(o.LoadBalancerDescriptions))
+
+		marker = o.NextMarker
+		if marker == nil {
+			break
+		}
+	}
+
+	return usage, nil
+}
+
+// cloudFormationStackUsage counts all stacks which are not deleted.
+func cloudFormationStackUsage(awsClients clientaws.Clients) (float64, error) {
+	var usage float64
+
+	var nextToken *string
+	for {
+		i := &cloudformation.DescribeStacksInput{
+			NextToken: nextToken,
+		}
+		o, err := awsClients.CloudFormation.DescribeStacks(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		usage += float64(len(o.Stacks))
+
+		nextToken = o.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+
+	return usage, nil
+}
+
+func elasticIPUsage(awsClients clientaws.Clients) (float64, error) {
+	i := &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []*string{aws.String("vpc")},
+			},
+		},
+	}
+	o, err := awsClients.EC2.DescribeAddresses(i)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return float64(len(o.Addresses)), nil
+}
+
+// loadBalancerUsage returns a quotaUsageFunc counting the application or
+// network load balancers, depending on the given type.
+func loadBalancerUsage(loadBalancerType string) quotaUsageFunc {
+	return func(awsClients clientaws.Clients) (float64, error) {
+		var usage float64
+
+		i := &elbv2.DescribeLoadBalancersInput{}
+		for {
+			o, err := awsClients.ELBv2.DescribeLoadBalancers(i)
+			if err != nil {
+				return 0, microerror.Mask(err)
+			}
+
+			for _, lb := range o.LoadBalancers {
+				if aws.StringValue(lb.Type) == loadBalancerType {
+					usage++
+				}
+			}
+
+			if o.NextMarker == nil {
+				break
+			}
+			i.SetMarker(*o.NextMarker)
+		}
+
+		return usage, nil
+	}
+}
+
+// natGatewayPerAZUsage returns the number of NAT gateways of the availability
+// zone containing the most of them, since the quota applies per zone.
+func natGatewayPerAZUsage(awsClients clientaws.Clients) (float64, error) {
+	subnetZones := map[string]string{}
+	{
+		i := &ec2.DescribeSubnetsInput{}
+		for {
+			o, err := awsClients.EC2.DescribeSubnets(i)
+			if err != nil {
+				return 0, microerror.Mask(err)
+			}
+
+			for _, s := range o.Subnets {
+				subnetZones[aws.StringValue(s.SubnetId)] = aws.StringValue(s.AvailabilityZone)
+			}
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	byZone := map[string]float64{}
+	{
+		i := &ec2.DescribeNatGatewaysInput{
+			Filter: []*ec2.Filter{
+				{
+					Name: aws.String("state"),
+					Values: []*string{
+						aws.String(ec2.NatGatewayStateAvailable),
+						aws.String(ec2.NatGatewayStatePending),
+					},
+				},
+			},
+			MaxResults: aws.Int64(1000),
+		}
+
+		for {
+			o, err := awsClients.EC2.DescribeNatGateways(i)
+			if err != nil {
+				return 0, microerror.Mask(err)
+			}
+
+			for _, n := range o.NatGateways {
+				byZone[subnetZones[aws.StringValue(n.SubnetId)]]++
+			}
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	var usage float64
+	for _, count := range byZone {
+		if count > usage {
+			usage = count
+		}
+	}
+
+	return usage, nil
+}
+
+func networkInterfaceUsage(awsClients clientaws.Clients) (float64, error) {
+	var usage float64
+
+	i := &ec2.DescribeNetworkInterfacesInput{
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		o, err := awsClients.EC2.DescribeNetworkInterfaces(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		usage += float64(len(o.NetworkInterfaces))
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return usage, nil
+}
+
+// onDemandVCPUUsages returns the vCPUs of all running on-demand instances
+// summed up per Running On-Demand instances quota, keyed by quota code. The
+// instances are listed once for all quotas. Quotas without instances are
+// returned with a usage of 0.
+func onDemandVCPUUsages(awsClients clientaws.Clients) (map[string]float64, error) {
+	usages := map[string]float64{}
+	for _, quotaCode := range onDemandVCPUQuotas {
+		usages[quotaCode] = 0
+	}
+
+	i := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("instance-state-name"),
+				Values: []*string{
+					aws.String(ec2.InstanceStateNamePending),
+					aws.String(ec2.InstanceStateNameRunning),
+				},
+			},
+		},
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		o, err := awsClients.EC2.DescribeInstances(i)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, reservation := range o.Reservations {
+			for _, instance := range reservation.Instances {
+				// Spot instances are counted against their own quota.
+				if instance.InstanceLifecycle != nil {
+					continue
+				}
+				quotaCode, ok := onDemandVCPUQuotas[instanceFamily(aws.StringValue(instance.InstanceType))]
+				if !ok {
+					continue
+				}
+				if instance.CpuOptions == nil {
+					continue
+				}
+
+				usages[quotaCode] += float64(aws.Int64Value(instance.CpuOptions.CoreCount) * aws.Int64Value(instance.CpuOptions.ThreadsPerCore))
+			}
+		}
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return usages, nil
+}
+
+func vpcUsage(awsClients clientaws.Clients) (float64, error) {
+	var usage float64
+
+	i := &ec2.DescribeVpcsInput{}
+	for {
+		o, err := awsClients.EC2.DescribeVpcs(i)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		usage += float64(len(o.Vpcs))
+
+		if o.NextToken == nil {
+			break
+		}
+		i.SetNextToken(*o.NextToken)
+	}
+
+	return usage, nil
+}
+
+// instanceFamily returns the letters the given instance type starts with,
+// e.g. m for m5.xlarge or inf for inf1.xlarge.
+func instanceFamily(instanceType string) string {
+	i := strings.IndexFunc(instanceType, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if i == -1 {
+		return instanceType
+	}
+
+	return instanceType[:i]
+}
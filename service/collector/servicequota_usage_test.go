@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+type ec2UsageMock struct {
+	ec2iface.EC2API
+
+	instances   []*ec2.Instance
+	natGateways []*ec2.NatGateway
+	subnets     []*ec2.Subnet
+	vpcs        []*ec2.Vpc
+
+	instanceCalls int
+	vpcCalls      int
+}
+
+func (e *ec2UsageMock) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	e.instanceCalls++
+	o := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{Instances: e.instances},
+		},
+	}
+
+	return o, nil
+}
+
+func (e *ec2UsageMock) DescribeNatGateways(*ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: e.natGateways}, nil
+}
+
+func (e *ec2UsageMock) DescribeSubnets(*ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: e.subnets}, nil
+}
+
+func (e *ec2UsageMock) DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	e.vpcCalls++
+	return &ec2.DescribeVpcsOutput{Vpcs: e.vpcs}, nil
+}
+
+func newTestInstance(instanceType string, vcpus int64, lifecycle *string) *ec2.Instance {
+	return &ec2.Instance{
+		CpuOptions: &ec2.CpuOptions{
+			CoreCount:      aws.Int64(vcpus / 2),
+			ThreadsPerCore: aws.Int64(2),
+		},
+		InstanceLifecycle: lifecycle,
+		InstanceType:      aws.String(instanceType),
+	}
+}
+
+func Test_onDemandVCPUUsages(t *testing.T) {
+	awsClients := clientaws.Clients{
+		EC2: &ec2UsageMock{
+			instances: []*ec2.Instance{
+				newTestInstance("m5.xlarge", 4, nil),
+				newTestInstance("t3.large", 2, nil),
+				// Spot instances do not count against the on-demand quotas.
+				newTestInstance("m5.xlarge", 4, aws.String(ec2.InstanceLifecycleTypeSpot)),
+				newTestInstance("p3.2xlarge", 8, nil),
+				newTestInstance("inf1.xlarge", 4, nil),
+				newTestInstance("g4dn.xlarge", 4, nil),
+				newTestInstance("vt1.3xlarge", 12, nil),
+				newTestInstance("im4gn.large", 2, nil),
+				newTestInstance("is4gen.medium", 2, nil),
+				newTestInstance("hpc6a.48xlarge", 96, nil),
+				newTestInstance("dl1.24xlarge", 96, nil),
+				newTestInstance("trn1.2xlarge", 8, nil),
+				// Instance families without vCPU quota are not counted.
+				newTestInstance("mac1.metal", 12, nil),
+			},
+		},
+	}
+
+	testCases := []struct {
+		quotaCode string
+		expected  float64
+	}{
+		{quotaCode: "L-1216C47A", expected: 10},
+		{quotaCode: "L-1945791B", expected: 4},
+		{quotaCode: "L-2C3B7624", expected: 8},
+		{quotaCode: "L-417A185B", expected: 8},
+		{quotaCode: "L-6E869C2A", expected: 96},
+		{quotaCode: "L-7295265B", expected: 0},
+		{quotaCode: "L-DB2E81BA", expected: 16},
+		{quotaCode: "L-F7808C92", expected: 96},
+	}
+
+	usages, err := onDemandVCPUUsages(awsClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.quotaCode, func(t *testing.T) {
+			usage, ok := usages[tc.quotaCode]
+			if !ok {
+				t.Fatalf("expected usage of %s", tc.quotaCode)
+			}
+			if usage != tc.expected {
+				t.Fatalf("expected usage %v, got %v", tc.expected, usage)
+			}
+		})
+	}
+}
+
+func Test_natGatewayPerAZUsage(t *testing.T) {
+	awsClients := clientaws.Clients{
+		EC2: &ec2UsageMock{
+			natGateways: []*ec2.NatGateway{
+				{SubnetId: aws.String("subnet-a1")},
+				{SubnetId: aws.String("subnet-a2")},
+				{SubnetId: aws.String("subnet-a2")},
+				{SubnetId: aws.String("subnet-b1")},
+			},
+			subnets: []*ec2.Subnet{
+				{AvailabilityZone: aws.String("eu-central-1a"), SubnetId: aws.String("subnet-a1")},
+				{AvailabilityZone: aws.String("eu-central-1a"), SubnetId: aws.String("subnet-a2")},
+				{AvailabilityZone: aws.String("eu-central-1b"), SubnetId: aws.String("subnet-b1")},
+			},
+		},
+	}
+
+	usage, err := natGatewayPerAZUsage(awsClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage != 3 {
+		t.Fatalf("expected usage 3, got %v", usage)
+	}
+}
+
+func Test_ServiceQuota_getUsage(t *testing.T) {
+	mock := &ec2UsageMock{
+		vpcs: []*ec2.Vpc{{}, {}},
+	}
+	awsClients := clientaws.Clients{
+		EC2:    mock,
+		Region: "eu-central-1",
+	}
+
+	s := newTestServiceQuota(t, nil)
+	q := ServiceQuotaSpec{ServiceCode: "vpc", QuotaCode: "L-F678F1CE", Name: "vpcs-per-region"}
+
+	// The second call is served from the cache.
+	for i := 0; i < 2; i++ {
+		usage, ok, err := s.getUsage(q, awsClients, "000000000000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("expected usage to be known")
+		}
+		if usage != 2 {
+			t.Fatalf("expected usage 2, got %v", usage)
+		}
+	}
+
+	if mock.vpcCalls != 1 {
+		t.Fatalf("expected 1 DescribeVpcs call, got %d", mock.vpcCalls)
+	}
+}
+
+// Test_ServiceQuota_getUsage_OnDemandVCPUs ensures that the instances are only
+// listed once for all On-Demand vCPU quotas.
+func Test_ServiceQuota_getUsage_OnDemandVCPUs(t *testing.T) {
+	mock := &ec2UsageMock{
+		instances: []*ec2.Instance{
+			newTestInstance("m5.xlarge", 4, nil),
+			newTestInstance("p3.2xlarge", 8, nil),
+		},
+	}
+	awsClients := clientaws.Clients{
+		EC2:    mock,
+		Region: "eu-central-1",
+	}
+
+	s := newTestServiceQuota(t, nil)
+
+	expected := map[string]float64{
+		"L-1216C47A": 4,
+		"L-417A185B": 8,
+	}
+	for _, q := range DefaultServiceQuotas {
+		if !isOnDemandVCPUQuota(q.QuotaCode) {
+			continue
+		}
+
+		usage, ok, err := s.getUsage(q, awsClients, "000000000000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("expected usage of %s to be known", q.QuotaCode)
+		}
+		if usage != expected[q.QuotaCode] {
+			t.Fatalf("expected usage %v of %s, got %v", expected[q.QuotaCode], q.QuotaCode, usage)
+		}
+	}
+
+	if mock.instanceCalls != 1 {
+		t.Fatalf("expected 1 DescribeInstances call, got %d", mock.instanceCalls)
+	}
+}
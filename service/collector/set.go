@@ -1,42 +1,105 @@
 package collector
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/giantswarm/exporterkit/collector"
-	"github.com/giantswarm/k8sclient/v5/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 )
 
 type SetConfig struct {
-	Clients k8sclient.Interface
-	Logger  micrologger.Logger
-
-	AWSConfig             clientaws.Config
-	InstallationName      string
-	TrustedAdvisorEnabled bool
+	// K8sClient is used to discover clusters and their credentials. It should
+	// be backed by an informer cache so that scrapes do not hit the Kubernetes
+	// API.
+	K8sClient client.Reader
+	Logger    micrologger.Logger
+
+	AWSConfig        clientaws.Config
+	InstallationName string
+	// PollingEnabled makes every collector refresh its metrics in the
+	// background on its own interval, so that scrapes only serve the latest
+	// refreshed metrics instead of calling AWS APIs.
+	PollingEnabled bool
+	// PollingInterval is the default interval collectors refresh their
+	// metrics in when polling is enabled.
+	PollingInterval time.Duration
+	// PollingIntervals overrides the polling interval of single collectors,
+	// keyed by collector name, e.g. servicequota.
+	PollingIntervals map[string]time.Duration
+	// RegionDiscoveryEnabled adds every region enabled in an account which
+	// contains resources of the installation to Regions.
+	RegionDiscoveryEnabled bool
+	// RegionDiscoveryTTL is the time discovered regions are cached for.
+	RegionDiscoveryTTL time.Duration
+	// Regions are the AWS regions metrics are collected in, in addition to the
+	// region of AWSConfig.
+	Regions []string
+	// ServiceQuotas are the service quotas to collect. The collector's
+	// default list is used when empty.
+	ServiceQuotas []ServiceQuotaSpec
+	// SnapshotRetention is the age after which EBS snapshots are reported as
+	// expired.
+	SnapshotRetention time.Duration
+	// TagComplianceRequiredTags are the tags every resource of the
+	// installation must have. The collector's default list is used when
+	// empty.
+	TagComplianceRequiredTags []string
+	TrustedAdvisorEnabled     bool
 }
 
+// defaultDiscoveryMaxAge is the age after which the discovery snapshot is not
+// served anymore. With polling enabled, snapshots may be as old as two polling
+// intervals if that is longer.
+const defaultDiscoveryMaxAge = 10 * time.Minute
+
 // Set is basically only a wrapper for the collector implementations.
 // It eases the initialization and prevents some weird import mess so we do not
 // have to alias packages. There is also the benefit of the helper type kept
 // private so we do not need to expose this magic.
 type Set struct {
 	*collector.Set
+
+	helper          *helper
+	logger          micrologger.Logger
+	pollers         []*poller
+	pollingInterval time.Duration
+}
+
+// namedCollector is a collector together with the name it is configured by.
+type namedCollector struct {
+	Name      string
+	Collector collector.Interface
 }
 
 func NewSet(config SetConfig) (*Set, error) {
 	var err error
 
+	// The discovery snapshot is refreshed on every scrape, or on the default
+	// polling interval when polling is enabled.
+	discoveryMaxAge := defaultDiscoveryMaxAge
+	if config.PollingEnabled && pollerMaxAgeIntervals*config.PollingInterval > discoveryMaxAge {
+		discoveryMaxAge = pollerMaxAgeIntervals * config.PollingInterval
+	}
+
 	var h *helper
 	{
-
 		c := helperConfig{
-			Clients: config.Clients,
-			Logger:  config.Logger,
-
-			AWSConfig: config.AWSConfig,
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+
+			AWSConfig:              config.AWSConfig,
+			DiscoveryMaxAge:        discoveryMaxAge,
+			InstallationName:       config.InstallationName,
+			RegionDiscoveryEnabled: config.RegionDiscoveryEnabled,
+			RegionDiscoveryTTL:     config.RegionDiscoveryTTL,
+			Regions:                config.Regions,
 		}
 
 		h, err = newHelper(c)
@@ -90,6 +153,119 @@ func NewSet(config SetConfig) (*Set, error) {
 		}
 	}
 
+	var ebsCollector *EBS
+	{
+		c := EBSConfig{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+		}
+
+		ebsCollector, err = NewEBS(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var eipCollector *EIP
+	{
+		c := EIPConfig{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+		}
+
+		eipCollector, err = NewEIP(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The orphaned and tag compliance collectors check the same resources, so
+	// they share their listings.
+	rc := newResourceCache(time.Minute * 5)
+
+	var orphanedCollector *Orphaned
+	{
+		c := OrphanedConfig{
+			Helper:        h,
+			Logger:        config.Logger,
+			ResourceCache: rc,
+
+			InstallationName: config.InstallationName,
+		}
+
+		orphanedCollector, err = NewOrphaned(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var controlPlaneDriftCollector *ControlPlaneDrift
+	{
+		c := ControlPlaneDriftConfig{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+		}
+
+		controlPlaneDriftCollector, err = NewControlPlaneDrift(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var nodePoolDriftCollector *NodePoolDrift
+	{
+		c := NodePoolDriftConfig{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+		}
+
+		nodePoolDriftCollector, err = NewNodePoolDrift(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var tagComplianceCollector *TagCompliance
+	{
+		c := TagComplianceConfig{
+			Helper:        h,
+			Logger:        config.Logger,
+			ResourceCache: rc,
+
+			InstallationName: config.InstallationName,
+			RequiredTags:     config.TagComplianceRequiredTags,
+		}
+
+		tagComplianceCollector, err = NewTagCompliance(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var snapshotCollector *Snapshot
+	{
+		c := SnapshotConfig{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+			Retention:        config.SnapshotRetention,
+		}
+
+		snapshotCollector, err = NewSnapshot(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var elbCollector *ELB
 	{
 		c := ELBConfig{
@@ -105,6 +281,21 @@ func NewSet(config SetConfig) (*Set, error) {
 		}
 	}
 
+	var elbv2Collector *ELBv2
+	{
+		c := ELBv2Config{
+			Helper: h,
+			Logger: config.Logger,
+
+			InstallationName: config.InstallationName,
+		}
+
+		elbv2Collector, err = NewELBv2(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var sqCollector *ServiceQuota
 	{
 		c := ServiceQuotaConfig{
@@ -112,6 +303,7 @@ func NewSet(config SetConfig) (*Set, error) {
 			Logger: config.Logger,
 
 			InstallationName: config.InstallationName,
+			Quotas:           config.ServiceQuotas,
 		}
 
 		sqCollector, err = NewServiceQuota(c)
@@ -191,26 +383,78 @@ func NewSet(config SetConfig) (*Set, error) {
 		}
 	}
 
-	var collectorSet *collector.Set
+	namedCollectors := []namedCollector{
+		{Name: subsystemCloudFormation, Collector: cfCollector},
+		{Name: subsystemASG, Collector: asgCollector},
+		{Name: subsystemEC2, Collector: ec2InstancesCollector},
+		{Name: subsystemEBS, Collector: ebsCollector},
+		{Name: subsystemSnapshot, Collector: snapshotCollector},
+		{Name: subsystemEIP, Collector: eipCollector},
+		{Name: subsystemOrphaned, Collector: orphanedCollector},
+		{Name: subsystemTagCompliance, Collector: tagComplianceCollector},
+		{Name: subsystemControlPlaneDrift, Collector: controlPlaneDriftCollector},
+		{Name: subsystemNodePoolDrift, Collector: nodePoolDriftCollector},
+		{Name: subsystemELB, Collector: elbCollector},
+		{Name: subsystemELBv2, Collector: elbv2Collector},
+		{Name: subsystemServiceQuota, Collector: sqCollector},
+		{Name: subsystemNAT, Collector: natCollector},
+		{Name: subsystemSubnet, Collector: subnetCollector},
+		{Name: subsystemUpdate, Collector: updateCollector},
+		{Name: subsystemVPC, Collector: vpcCollector},
+	}
+
+	if config.TrustedAdvisorEnabled {
+		config.Logger.Log("level", "debug", "message", "trusted advisor collector is enabled")
+		namedCollectors = append(namedCollectors, namedCollector{Name: subsystemTrustedAdvisor, Collector: trustedAdvisorCollector})
+	}
+
+	var collectors []collector.Interface
+	var pollers []*poller
 	{
-		c := collector.SetConfig{
-			Collectors: []collector.Interface{
-				cfCollector,
-				asgCollector,
-				ec2InstancesCollector,
-				elbCollector,
-				sqCollector,
-				natCollector,
-				subnetCollector,
-				updateCollector,
-				vpcCollector,
-			},
-			Logger: config.Logger,
+		known := map[string]bool{}
+		for _, n := range namedCollectors {
+			known[n.Name] = true
+		}
+		for name := range config.PollingIntervals {
+			if !known[name] {
+				return nil, microerror.Maskf(invalidConfigError, "%T.PollingIntervals must only contain known collectors, got %#q", config, name)
+			}
 		}
 
-		if config.TrustedAdvisorEnabled {
-			config.Logger.Log("level", "debug", "message", "trusted advisor collector is enabled")
-			c.Collectors = append(c.Collectors, trustedAdvisorCollector)
+		for _, n := range namedCollectors {
+			if !config.PollingEnabled {
+				collectors = append(collectors, n.Collector)
+				continue
+			}
+
+			interval := config.PollingInterval
+			if i, ok := config.PollingIntervals[n.Name]; ok {
+				interval = i
+			}
+
+			c := pollerConfig{
+				Collector: n.Collector,
+				Logger:    config.Logger,
+
+				Interval: interval,
+				Name:     n.Name,
+			}
+
+			p, err := newPoller(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			collectors = append(collectors, p)
+			pollers = append(pollers, p)
+		}
+	}
+
+	var collectorSet *collector.Set
+	{
+		c := collector.SetConfig{
+			Collectors: collectors,
+			Logger:     config.Logger,
 		}
 
 		collectorSet, err = collector.NewSet(c)
@@ -221,7 +465,89 @@ func NewSet(config SetConfig) (*Set, error) {
 
 	s := &Set{
 		Set: collectorSet,
+
+		helper:          h,
+		logger:          config.Logger,
+		pollers:         pollers,
+		pollingInterval: config.PollingInterval,
 	}
 
 	return s, nil
 }
+
+// Boot registers the Set itself instead of the embedded exporterkit set, so
+// that the metrics the helper tracks about the collection itself are
+// described and collected alongside the metrics of all collectors. When
+// polling is enabled, the background refreshes of all collectors are started
+// as well.
+func (s *Set) Boot(ctx context.Context) error {
+	if len(s.pollers) > 0 {
+		go s.pollDiscovery(ctx)
+	}
+
+	s.logger.LogCtx(ctx, "level", "debug", "message", "registering collector")
+
+	err := prometheus.Register(s)
+	if collector.IsAlreadyRegisteredError(err) {
+		s.logger.LogCtx(ctx, "level", "debug", "message", "collector already registered")
+	} else if err != nil {
+		s.logger.LogCtx(ctx, "level", "error", "message", "failed registering collector", "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+	} else {
+		s.logger.LogCtx(ctx, "level", "debug", "message", "registered collector")
+	}
+
+	return nil
+}
+
+// Collect refreshes the discovery snapshot shared by all collectors once per
+// scrape before the collectors are executed. When polling is enabled the
+// collectors only serve their latest refreshed metrics and the snapshot is
+// refreshed in the background instead.
+func (s *Set) Collect(ch chan<- prometheus.Metric) {
+	if len(s.pollers) == 0 {
+		s.refreshDiscovery(context.Background())
+	}
+
+	s.Set.Collect(ch)
+	s.helper.Collect(ch)
+}
+
+func (s *Set) Describe(ch chan<- *prometheus.Desc) {
+	s.Set.Describe(ch)
+	s.helper.Describe(ch)
+}
+
+// pollDiscovery refreshes the discovery snapshot right away and starts the
+// background refreshes of all collectors afterwards, so that they do not all
+// compute the snapshot at once. The snapshot is then refreshed on the default
+// polling interval until the given context is done.
+func (s *Set) pollDiscovery(ctx context.Context) {
+	s.refreshDiscovery(ctx)
+
+	for _, p := range s.pollers {
+		go p.Boot(ctx)
+	}
+
+	ticker := time.NewTicker(s.pollingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.refreshDiscovery(ctx)
+	}
+}
+
+// refreshDiscovery refreshes the discovery snapshot shared by all collectors.
+// Failures are logged, since the previous snapshot is served until it exceeds
+// its maximum age.
+func (s *Set) refreshDiscovery(ctx context.Context) {
+	_, err := s.helper.Refresh(ctx)
+	if err != nil {
+		s.logger.Log("level", "error", "message", "failed refreshing discovery snapshot, falling back to the previous one", "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+	}
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+// Test_NewSet_PollingDisabled ensures that the set can be created in its
+// default configuration, in which polling is disabled and no polling interval
+// is configured.
+func Test_NewSet_PollingDisabled(t *testing.T) {
+	c := SetConfig{
+		K8sClient: fake.NewFakeClientWithScheme(newTestScheme(t)),
+		Logger:    microloggertest.New(),
+
+		AWSConfig: clientaws.Config{
+			AccessKeyID:     "id",
+			AccessKeySecret: "secret",
+			Region:          "eu-central-1",
+		},
+		InstallationName:  "test",
+		SnapshotRetention: 720 * time.Hour,
+	}
+
+	s, err := NewSet(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.helper.discoveryMaxAge != defaultDiscoveryMaxAge {
+		t.Fatalf("expected discovery max age %s, got %s", defaultDiscoveryMaxAge, s.helper.discoveryMaxAge)
+	}
+	if len(s.pollers) != 0 {
+		t.Fatalf("expected no pollers, got %d", len(s.pollers))
+	}
+}
+
+// discoveryCollectorMock reports the number of discovery lists which happened
+// before it requested the discovery snapshot itself.
+type discoveryCollectorMock struct {
+	helper *helper
+	reader *failingReader
+
+	lists chan int
+}
+
+func (c *discoveryCollectorMock) Collect(ch chan<- prometheus.Metric) error {
+	c.lists <- c.reader.lists
+
+	_, err := c.helper.Discovery(context.Background())
+	return err
+}
+
+func (c *discoveryCollectorMock) Describe(ch chan<- *prometheus.Desc) error {
+	return nil
+}
+
+// Test_Set_pollDiscovery ensures that the discovery snapshot is refreshed
+// before the pollers are started, so that they do not all compute it at once.
+func Test_Set_pollDiscovery(t *testing.T) {
+	reader := &failingReader{err: errors.New("test error")}
+	h := newTestHelper(t, reader)
+
+	c := &discoveryCollectorMock{
+		helper: h,
+		reader: reader,
+
+		lists: make(chan int, 1),
+	}
+
+	p, err := newPoller(pollerConfig{
+		Collector: c,
+		Logger:    microloggertest.New(),
+
+		Interval: time.Hour,
+		Name:     "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Set{
+		helper:          h,
+		logger:          microloggertest.New(),
+		pollers:         []*poller{p},
+		pollingInterval: time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.pollDiscovery(ctx)
+
+	select {
+	case lists := <-c.lists:
+		if lists != 1 {
+			t.Fatalf("expected 1 list before the poller started, got %d", lists)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the poller to be started")
+	}
+}
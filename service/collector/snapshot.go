@@ -0,0 +1,273 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	labelSnapshot = "snapshot"
+)
+
+const (
+	subsystemSnapshot = "snapshot"
+)
+
+var (
+	snapshotNewestAgeDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemSnapshot, "newest_age_seconds"),
+		"Age of the newest completed EBS snapshot.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelInstallation,
+			labelSnapshot,
+			labelRegion,
+		},
+		nil,
+	)
+	snapshotsDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemSnapshot, "count"),
+		"Number of EBS snapshots.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelInstallation,
+			labelSnapshot,
+			labelRegion,
+		},
+		nil,
+	)
+	snapshotsSizeDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemSnapshot, "size_bytes"),
+		"Total size of the volumes EBS snapshots were taken from in bytes.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelInstallation,
+			labelSnapshot,
+			labelRegion,
+		},
+		nil,
+	)
+	snapshotsExpiredDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemSnapshot, "expired_count"),
+		"Number of EBS snapshots older than the configured retention.",
+		[]string{
+			labelAccountID,
+			labelCluster,
+			labelInstallation,
+			labelSnapshot,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type SnapshotConfig struct {
+	Helper *helper
+	Logger micrologger.Logger
+
+	InstallationName string
+	// Retention is the age after which snapshots are reported as expired.
+	Retention time.Duration
+}
+
+// Snapshot collects metrics about the EBS snapshots of the installation,
+// grouped by cluster and the value of their giantswarm.io/snapshot tag.
+type Snapshot struct {
+	helper *helper
+	logger micrologger.Logger
+
+	installationName string
+	// now is only meant to be replaced in tests.
+	now       func() time.Time
+	retention time.Duration
+}
+
+// snapshotGroup holds the aggregated values of the snapshots of a cluster
+// sharing the same giantswarm.io/snapshot tag.
+type snapshotGroup struct {
+	Count   float64
+	Expired float64
+	// Newest is the start time of the newest completed snapshot. It is zero
+	// if no snapshot of the group is completed.
+	Newest time.Time
+	Size   float64
+}
+
+type snapshotGroupKey struct {
+	Cluster  string
+	Snapshot string
+}
+
+func NewSnapshot(config SnapshotConfig) (*Snapshot, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+	if config.Retention <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Retention must be greater than 0", config)
+	}
+
+	s := &Snapshot{
+		helper: config.Helper,
+		logger: config.Logger,
+
+		installationName: config.InstallationName,
+		now:              time.Now,
+		retention:        config.Retention,
+	}
+
+	return s, nil
+}
+
+func (s *Snapshot) Collect(ch chan<- prometheus.Metric) error {
+	err := s.helper.CollectForAccounts(ch, subsystemSnapshot, s.collectForAccount)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *Snapshot) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- snapshotNewestAgeDesc
+	ch <- snapshotsDesc
+	ch <- snapshotsSizeDesc
+	ch <- snapshotsExpiredDesc
+	return nil
+}
+
+func (s *Snapshot) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	var snapshots []*ec2.Snapshot
+	{
+		i := &ec2.DescribeSnapshotsInput{
+			Filters: []*ec2.Filter{
+				{
+					Name: aws.String(fmt.Sprintf("tag:%s", key.TagInstallation)),
+					Values: []*string{
+						aws.String(s.installationName),
+					},
+				},
+			},
+			MaxResults: aws.Int64(1000),
+			// Public and shared snapshots of other accounts are ignored.
+			OwnerIds: []*string{
+				aws.String("self"),
+			},
+		}
+
+		for {
+			o, err := awsClients.EC2.DescribeSnapshots(i)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			snapshots = append(snapshots, o.Snapshots...)
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
+	}
+
+	now := s.now()
+	groups := map[snapshotGroupKey]*snapshotGroup{}
+
+	for _, snapshot := range snapshots {
+		var k snapshotGroupKey
+		for _, tag := range snapshot.Tags {
+			switch aws.StringValue(tag.Key) {
+			case tagCluster:
+				k.Cluster = aws.StringValue(tag.Value)
+			case key.TagSnapshot:
+				k.Snapshot = aws.StringValue(tag.Value)
+			}
+		}
+
+		g, ok := groups[k]
+		if !ok {
+			g = &snapshotGroup{}
+			groups[k] = g
+		}
+
+		startTime := aws.TimeValue(snapshot.StartTime)
+
+		g.Count++
+		g.Size += float64(aws.Int64Value(snapshot.VolumeSize) * gibibyte)
+		if now.Sub(startTime) > s.retention {
+			g.Expired++
+		}
+		if aws.StringValue(snapshot.State) == ec2.SnapshotStateCompleted && startTime.After(g.Newest) {
+			g.Newest = startTime
+		}
+	}
+
+	for k, g := range groups {
+		ch <- prometheus.MustNewConstMetric(
+			snapshotsDesc,
+			prometheus.GaugeValue,
+			g.Count,
+			accountID,
+			k.Cluster,
+			s.installationName,
+			k.Snapshot,
+			awsClients.Region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			snapshotsSizeDesc,
+			prometheus.GaugeValue,
+			g.Size,
+			accountID,
+			k.Cluster,
+			s.installationName,
+			k.Snapshot,
+			awsClients.Region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			snapshotsExpiredDesc,
+			prometheus.GaugeValue,
+			g.Expired,
+			accountID,
+			k.Cluster,
+			s.installationName,
+			k.Snapshot,
+			awsClients.Region,
+		)
+
+		// Groups without completed snapshot have no age, so that alerts on
+		// the absence of the metric fire for them.
+		if g.Newest.IsZero() {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			snapshotNewestAgeDesc,
+			prometheus.GaugeValue,
+			now.Sub(g.Newest).Seconds(),
+			accountID,
+			k.Cluster,
+			s.installationName,
+			k.Snapshot,
+			awsClients.Region,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+type snapshotMock struct {
+	ec2iface.EC2API
+
+	snapshots []*ec2.Snapshot
+}
+
+func (s *snapshotMock) DescribeSnapshots(i *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+	o := &ec2.DescribeSnapshotsOutput{}
+	for _, snapshot := range s.snapshots {
+		if hasTags(snapshot.Tags, i.Filters) {
+			o.Snapshots = append(o.Snapshots, snapshot)
+		}
+	}
+
+	return o, nil
+}
+
+func testSnapshot(id string, installation string, cluster string, state string, startTime time.Time) *ec2.Snapshot {
+	return &ec2.Snapshot{
+		SnapshotId: aws.String(id),
+		StartTime:  aws.Time(startTime),
+		State:      aws.String(state),
+		Tags: append(
+			ec2Tags(installation, cluster),
+			&ec2.Tag{Key: aws.String(key.TagSnapshot), Value: aws.String("etcd")},
+		),
+		VolumeSize: aws.Int64(10),
+	}
+}
+
+func Test_Snapshot_collectForAccount(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	mock := &snapshotMock{
+		snapshots: []*ec2.Snapshot{
+			testSnapshot("snap-000001", "test", "al9qy", ec2.SnapshotStateCompleted, now.Add(-2*time.Hour)),
+			testSnapshot("snap-000002", "test", "al9qy", ec2.SnapshotStateCompleted, now.Add(-48*time.Hour)),
+			// Pending snapshots are not considered for the newest snapshot.
+			testSnapshot("snap-000003", "test", "al9qy", ec2.SnapshotStatePending, now.Add(-time.Minute)),
+			// Snapshots of clusters without completed snapshot have no age.
+			testSnapshot("snap-000004", "test", "x7k2e", ec2.SnapshotStateError, now.Add(-time.Hour)),
+			// Snapshots of other installations are ignored.
+			testSnapshot("snap-000005", "other", "b4c1d", ec2.SnapshotStateCompleted, now.Add(-time.Hour)),
+			// Snapshots not tagged with their purpose are grouped separately.
+			{
+				SnapshotId: aws.String("snap-000006"),
+				StartTime:  aws.Time(now.Add(-10 * 24 * time.Hour)),
+				State:      aws.String(ec2.SnapshotStateCompleted),
+				Tags:       ec2Tags("test", "al9qy"),
+				VolumeSize: aws.Int64(100),
+			},
+		},
+	}
+
+	s, err := NewSnapshot(SnapshotConfig{
+		Helper: &helper{},
+		Logger: microloggertest.New(),
+
+		InstallationName: "test",
+		Retention:        24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+
+	awsClients := clientaws.Clients{
+		EC2:    mock,
+		Region: "eu-central-1",
+	}
+
+	c := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			return s.collectForAccount(ch, awsClients, "000000000000")
+		},
+		describe: s.Describe,
+	}
+
+	compareGolden(t, "snapshots", gatherText(t, c))
+}
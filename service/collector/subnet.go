@@ -12,7 +12,6 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/controller/key"
@@ -46,6 +45,7 @@ var (
 			labelAvailabilityZone,
 			labelAccount,
 			labelVPC,
+			labelRegion,
 		},
 		nil,
 	)
@@ -65,6 +65,7 @@ var (
 			labelAvailabilityZone,
 			labelAccount,
 			labelVPC,
+			labelRegion,
 		},
 		nil,
 	)
@@ -131,9 +132,9 @@ func newSubnetCache(expiration time.Duration) *subnetCache {
 	return cache
 }
 
-func (n *subnetCache) Get(key string) (*subnetInfoResponse, error) {
+func (n *subnetCache) Get(accountID string, region string) (*subnetInfoResponse, error) {
 	var c subnetInfoResponse
-	raw, exists := n.cache.Get(getSubnetCacheKey(key))
+	raw, exists := n.cache.Get(getSubnetCacheKey(accountID, region))
 	if exists {
 		err := json.Unmarshal(raw, &c)
 		if err != nil {
@@ -144,48 +145,25 @@ func (n *subnetCache) Get(key string) (*subnetInfoResponse, error) {
 	return &c, nil
 }
 
-func (n *subnetCache) Set(key string, content subnetInfoResponse) error {
+func (n *subnetCache) Set(accountID string, region string, content subnetInfoResponse) error {
 	contentSerialized, err := json.Marshal(content)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	n.cache.Set(getSubnetCacheKey(key), contentSerialized)
+	n.cache.Set(getSubnetCacheKey(accountID, region), contentSerialized)
 
 	return nil
 }
 
-func getSubnetCacheKey(key string) string {
-	return prefixSubnetcacheKey + key
+func getSubnetCacheKey(accountID string, region string) string {
+	return prefixSubnetcacheKey + accountID + "/" + region
 }
 
 func (e *Subnet) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := e.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := e.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := e.collectForAccount(context.Background(), ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := e.helper.CollectForAccounts(ch, subsystemSubnet, func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		return e.collectForAccount(context.Background(), ch, awsClients, accountID)
+	})
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -199,15 +177,10 @@ func (e *Subnet) Describe(ch chan<- *prometheus.Desc) error {
 	return nil
 }
 
-func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	account, err := e.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
 	var subnetInfo *subnetInfoResponse
 	// Check if response is cached
-	subnetInfo, err = e.cache.Get(account)
+	subnetInfo, err := e.cache.Get(accountID, awsClients.Region)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -220,7 +193,7 @@ func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Met
 		}
 
 		if subnetInfo != nil {
-			err = e.cache.Set(account, *subnetInfo)
+			err = e.cache.Set(accountID, awsClients.Region, *subnetInfo)
 			if err != nil {
 				return microerror.Mask(err)
 			}
@@ -233,7 +206,7 @@ func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Met
 				subnetsDesc,
 				prometheus.GaugeValue,
 				float64(subnet.AvailableIPs),
-				account,
+				accountID,
 				subnet.Tags["CidrBlock"],
 				subnet.Tags[key.TagCluster],
 				subnet.Name,
@@ -245,13 +218,14 @@ func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Met
 				subnet.Tags["AvailabilityZone"],
 				subnet.Tags["OwnerId"],
 				subnet.Tags["VpcId"],
+				awsClients.Region,
 			)
 
 			ch <- prometheus.MustNewConstMetric(
 				subnetsPercentageDesc,
 				prometheus.GaugeValue,
 				subnet.AvailableIPPercentage,
-				account,
+				accountID,
 				subnet.Tags["CidrBlock"],
 				subnet.Tags[key.TagCluster],
 				subnet.Name,
@@ -263,6 +237,7 @@ func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Met
 				subnet.Tags["AvailabilityZone"],
 				subnet.Tags["OwnerId"],
 				subnet.Tags["VpcId"],
+				awsClients.Region,
 			)
 		}
 	}
@@ -273,13 +248,27 @@ func (e *Subnet) collectForAccount(ctx context.Context, ch chan<- prometheus.Met
 // getSubnetInfoFromAPI collects Subnet Info from AWS API
 func (e *Subnet) getSubnetInfoFromAPI(ctx context.Context, awsClients clientaws.Clients) (*subnetInfoResponse, error) {
 	var res subnetInfoResponse
-	o, err := awsClients.EC2.DescribeSubnets(&ec2.DescribeSubnetsInput{})
-	if err != nil {
-		return nil, microerror.Mask(err)
+
+	var describedSubnets []*ec2.Subnet
+	{
+		i := &ec2.DescribeSubnetsInput{}
+		for {
+			o, err := awsClients.EC2.DescribeSubnets(i)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			describedSubnets = append(describedSubnets, o.Subnets...)
+
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
 	}
 
+	var err error
 	var subnets []subnetInfo
-	for _, sn := range o.Subnets {
+	for _, sn := range describedSubnets {
 		subnet := subnetInfo{
 			Name:         *sn.SubnetId,
 			AvailableIPs: *sn.AvailableIpAddressCount,
@@ -0,0 +1,223 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+const (
+	labelTag = "tag"
+)
+
+const (
+	subsystemTagCompliance = "tag_compliance"
+)
+
+const (
+	// reasonInstallationMissing is reported for resources tagged with a
+	// cluster of the installation but not with the installation itself.
+	reasonInstallationMissing = "installation_missing"
+	// reasonOrganizationMismatch is reported for resources tagged with an
+	// organization other than the one of their cluster's AWSCluster CR.
+	reasonOrganizationMismatch = "organization_mismatch"
+)
+
+// DefaultRequiredTags are the tags every resource of the installation is
+// checked for if no other tags are configured.
+var DefaultRequiredTags = []string{
+	key.TagInstallation,
+	key.TagCluster,
+	key.TagOrganization,
+}
+
+var (
+	tagComplianceMissingDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemTagCompliance, "resources_missing_tag"),
+		"Number of AWS resources of the installation missing a required tag.",
+		[]string{
+			labelAccountID,
+			labelResourceType,
+			labelTag,
+			labelRegion,
+		},
+		nil,
+	)
+	tagComplianceInconsistentDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemTagCompliance, "resources_inconsistent_tags"),
+		"Number of AWS resources of the installation with tags contradicting each other or the cluster they belong to.",
+		[]string{
+			labelAccountID,
+			labelResourceType,
+			labelCluster,
+			labelReason,
+			labelRegion,
+		},
+		nil,
+	)
+)
+
+type TagComplianceConfig struct {
+	Helper        *helper
+	Logger        micrologger.Logger
+	ResourceCache *resourceCache
+
+	InstallationName string
+	// RequiredTags are the tags every resource of the installation must have.
+	// DefaultRequiredTags are used when empty.
+	RequiredTags []string
+}
+
+// TagCompliance collects the number of resources of the installation with
+// missing or inconsistent tags. Resources belong to the installation if they
+// are tagged with it, or if they are not tagged with any installation but with
+// one of its clusters.
+type TagCompliance struct {
+	helper        *helper
+	logger        micrologger.Logger
+	resourceCache *resourceCache
+
+	installationName string
+	requiredTags     []string
+}
+
+func NewTagCompliance(config TagComplianceConfig) (*TagCompliance, error) {
+	if config.Helper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Helper must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.ResourceCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ResourceCache must not be empty", config)
+	}
+
+	if config.InstallationName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.InstallationName must not be empty", config)
+	}
+
+	requiredTags := config.RequiredTags
+	if len(requiredTags) == 0 {
+		requiredTags = DefaultRequiredTags
+	}
+
+	t := &TagCompliance{
+		helper:        config.Helper,
+		logger:        config.Logger,
+		resourceCache: config.ResourceCache,
+
+		installationName: config.InstallationName,
+		requiredTags:     requiredTags,
+	}
+
+	return t, nil
+}
+
+func (t *TagCompliance) Collect(ch chan<- prometheus.Metric) error {
+	d, err := t.helper.Discovery(context.Background())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	organizations := map[string]string{}
+	for _, cluster := range d.Clusters.Items {
+		organizations[key.ClusterID(cluster)] = key.OrganizationID(cluster)
+	}
+
+	collectFunc := func(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+		return t.collectForAccount(ch, awsClients, accountID, organizations)
+	}
+
+	err = t.helper.CollectForAccounts(ch, subsystemTagCompliance, collectFunc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (t *TagCompliance) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- tagComplianceMissingDesc
+	ch <- tagComplianceInconsistentDesc
+	return nil
+}
+
+// collectForAccount checks the tags of all managed resources against the
+// given organizations, keyed by the ID of the existing clusters.
+func (t *TagCompliance) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string, organizations map[string]string) error {
+	type inconsistency struct {
+		Cluster string
+		Reason  string
+	}
+
+	for resourceType := range managedResourceTypes {
+		// Resources without installation tag are listed as well, so that they
+		// can be found by their cluster tag.
+		resources, err := t.resourceCache.List(awsClients, accountID, resourceType)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		// Every required tag is reported, even if no resource misses it.
+		missing := map[string]float64{}
+		for _, tag := range t.requiredTags {
+			missing[tag] = 0
+		}
+		inconsistent := map[inconsistency]float64{}
+
+		for _, tags := range resources {
+			installation := tags[key.TagInstallation]
+			cluster := tags[key.TagCluster]
+			organization, clusterExists := organizations[cluster]
+
+			if installation == "" && clusterExists {
+				inconsistent[inconsistency{Cluster: cluster, Reason: reasonInstallationMissing}]++
+			} else if installation != t.installationName {
+				continue
+			}
+
+			for _, tag := range t.requiredTags {
+				if tags[tag] == "" {
+					missing[tag]++
+				}
+			}
+
+			// Missing organization tags are already reported above.
+			if clusterExists && organization != "" && tags[key.TagOrganization] != "" && tags[key.TagOrganization] != organization {
+				inconsistent[inconsistency{Cluster: cluster, Reason: reasonOrganizationMismatch}]++
+			}
+		}
+
+		for tag, count := range missing {
+			ch <- prometheus.MustNewConstMetric(
+				tagComplianceMissingDesc,
+				prometheus.GaugeValue,
+				count,
+				accountID,
+				resourceType,
+				tag,
+				awsClients.Region,
+			)
+		}
+
+		for i, count := range inconsistent {
+			ch <- prometheus.MustNewConstMetric(
+				tagComplianceInconsistentDesc,
+				prometheus.GaugeValue,
+				count,
+				accountID,
+				resourceType,
+				i.Cluster,
+				i.Reason,
+				awsClients.Region,
+			)
+		}
+	}
+
+	return nil
+}
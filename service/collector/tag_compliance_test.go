@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+)
+
+func Test_TagCompliance_collectForAccount(t *testing.T) {
+	awsClients := clientaws.Clients{
+		CloudFormation: &cloudFormationMock{},
+		EC2: &taggedEC2Mock{
+			tags: [][]*ec2.Tag{
+				ec2Tags("test", "al9qy"),
+				// Resources of a cluster of the installation without
+				// installation tag.
+				{
+					{Key: aws.String(tagCluster), Value: aws.String("al9qy")},
+					{Key: aws.String(tagOrganization), Value: aws.String("giantswarm")},
+				},
+				// Resources tagged with another organization than the one of
+				// their cluster.
+				{
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+					{Key: aws.String(tagCluster), Value: aws.String("al9qy")},
+					{Key: aws.String(tagOrganization), Value: aws.String("acme")},
+				},
+				// Resources of the installation not belonging to any cluster.
+				{
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+				},
+				// Resources of other installations or without any tags are
+				// ignored.
+				ec2Tags("other", "b4c1d"),
+				{},
+			},
+		},
+		ELB:    &elbMock{},
+		ELBv2:  &elbv2Mock{},
+		Region: "eu-central-1",
+	}
+
+	c, err := NewTagCompliance(TagComplianceConfig{
+		Helper:        &helper{},
+		Logger:        microloggertest.New(),
+		ResourceCache: newResourceCache(time.Minute),
+
+		InstallationName: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	organizations := map[string]string{
+		"al9qy": "giantswarm",
+	}
+
+	a := accountCollectorFunc{
+		t: t,
+
+		collect: func(ch chan<- prometheus.Metric) error {
+			return c.collectForAccount(ch, awsClients, "000000000000", organizations)
+		},
+		describe: c.Describe,
+	}
+
+	compareGolden(t, "tag_compliance", gatherText(t, a))
+}
@@ -1,7 +1,6 @@
 package collector
 
 import (
-	"context"
 	"strconv"
 
 	"github.com/aws/aws-sdk-go/service/support"
@@ -33,7 +32,12 @@ const (
 )
 
 const (
-	labelRegion  = "region"
+	// subsystemTrustedAdvisor identifies this collector in the metrics about
+	// the collection itself.
+	subsystemTrustedAdvisor = "trusted_advisor"
+)
+
+const (
 	labelService = "service"
 )
 
@@ -99,32 +103,7 @@ func NewTrustedAdvisor(config TrustedAdvisorConfig) (*TrustedAdvisor, error) {
 }
 
 func (t *TrustedAdvisor) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := t.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := t.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := t.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := t.helper.CollectForAccounts(ch, subsystemTrustedAdvisor, t.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -138,10 +117,11 @@ func (t *TrustedAdvisor) Describe(ch chan<- *prometheus.Desc) error {
 	return nil
 }
 
-func (t *TrustedAdvisor) collectForAccount(ch chan<- prometheus.Metric, awsClients aws.Clients) error {
-	accountID, err := t.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
+func (t *TrustedAdvisor) collectForAccount(ch chan<- prometheus.Metric, awsClients aws.Clients, accountID string) error {
+	// Trusted Advisor is a global service reporting on all regions, so it is
+	// only asked once per account.
+	if awsClients.Region != t.helper.region {
+		return nil
 	}
 
 	checks, err := t.getTrustedAdvisorChecks(awsClients)
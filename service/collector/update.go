@@ -13,7 +13,6 @@ import (
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/senseyeio/duration"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -92,22 +91,15 @@ func NewUpdate(config UpdateConfig) (*Update, error) {
 }
 
 func (np *Update) Collect(ch chan<- prometheus.Metric) error {
-	ctx := context.Background()
-
-	var list infrastructurev1alpha3.AWSMachineDeploymentList
-	{
-		err := np.helper.clients.CtrlClient().List(
-			ctx,
-			&list,
-		)
-		if err != nil {
-			return microerror.Mask(err)
-		}
+	d, err := np.helper.Discovery(context.Background())
+	if err != nil {
+		return microerror.Mask(err)
 	}
+
 	var nodePools []updateInfo
 	{
-		for _, md := range list.Items {
-			batch, pause, err := np.getUpdateAnnotations(md)
+		for _, md := range d.MachineDeployments.Items {
+			batch, pause, err := np.getUpdateAnnotations(d, md)
 			if err != nil {
 				return microerror.Mask(err)
 			}
@@ -163,7 +155,7 @@ func (np *Update) Describe(ch chan<- *prometheus.Desc) error {
 	return nil
 }
 
-func (np *Update) getUpdateAnnotations(md infrastructurev1alpha3.AWSMachineDeployment) (string, string, error) {
+func (np *Update) getUpdateAnnotations(d *discovery, md infrastructurev1alpha3.AWSMachineDeployment) (string, string, error) {
 	var batch, time string
 
 	// get info from AWSMachineDeployment
@@ -178,19 +170,16 @@ func (np *Update) getUpdateAnnotations(md infrastructurev1alpha3.AWSMachineDeplo
 	// get info from AWSCluster
 	var cl infrastructurev1alpha3.AWSCluster
 	{
-		var clusterList infrastructurev1alpha3.AWSClusterList
-		err := np.helper.clients.CtrlClient().List(
-			context.Background(),
-			&clusterList,
-			client.MatchingLabels{label.Cluster: md.Labels[label.Cluster]},
-		)
-		if err != nil {
-			return "", "", microerror.Mask(err)
+		var clusters []infrastructurev1alpha3.AWSCluster
+		for _, c := range d.Clusters.Items {
+			if c.Labels[label.Cluster] == md.Labels[label.Cluster] {
+				clusters = append(clusters, c)
+			}
 		}
-		if len(clusterList.Items) != 1 {
-			return "", "", microerror.Maskf(notFoundError, "Tried to find one AWSCluster CR with ID %s, found %v.", md.Labels[label.Cluster], len(clusterList.Items))
+		if len(clusters) != 1 {
+			return "", "", microerror.Maskf(notFoundError, "Tried to find one AWSCluster CR with ID %s, found %v.", md.Labels[label.Cluster], len(clusters))
 		}
-		cl = clusterList.Items[0]
+		cl = clusters[0]
 	}
 
 	if cl.Annotations != nil {
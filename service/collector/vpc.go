@@ -1,13 +1,10 @@
 package collector
 
 import (
-	"context"
-
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sync/errgroup"
 
 	clientaws "github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/service/controller/key"
@@ -38,6 +35,7 @@ var (
 			labelOrganization,
 			labelStack,
 			labelState,
+			labelRegion,
 		},
 		nil,
 	)
@@ -80,32 +78,7 @@ func NewVPC(config VPCConfig) (*VPC, error) {
 }
 
 func (v *VPC) Collect(ch chan<- prometheus.Metric) error {
-	reconciledClusters, err := v.helper.ListReconciledClusters()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	awsClientsList, err := v.helper.GetAWSClients(context.Background(), reconciledClusters)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	var g errgroup.Group
-
-	for _, item := range awsClientsList {
-		awsClients := item
-
-		g.Go(func() error {
-			err := v.collectForAccount(ch, awsClients)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			return nil
-		})
-	}
-
-	err = g.Wait()
+	err := v.helper.CollectForAccounts(ch, subsystemVPC, v.collectForAccount)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -118,18 +91,25 @@ func (v *VPC) Describe(ch chan<- *prometheus.Desc) error {
 	return nil
 }
 
-func (v *VPC) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients) error {
-	o, err := awsClients.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{})
-	if err != nil {
-		return microerror.Mask(err)
-	}
+func (v *VPC) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaws.Clients, accountID string) error {
+	var vpcs []*ec2.Vpc
+	{
+		i := &ec2.DescribeVpcsInput{}
+		for {
+			o, err := awsClients.EC2.DescribeVpcs(i)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			vpcs = append(vpcs, o.Vpcs...)
 
-	accountID, err := v.helper.AWSAccountID(awsClients)
-	if err != nil {
-		return microerror.Mask(err)
+			if o.NextToken == nil {
+				break
+			}
+			i.SetNextToken(*o.NextToken)
+		}
 	}
 
-	for _, vpc := range o.Vpcs {
+	for _, vpc := range vpcs {
 		var cluster, installation, name, organization, stackName string
 
 		for _, tag := range vpc.Tags {
@@ -164,6 +144,7 @@ func (v *VPC) collectForAccount(ch chan<- prometheus.Metric, awsClients clientaw
 			organization,
 			stackName,
 			*vpc.State,
+			awsClients.Region,
 		)
 	}
 
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/controller/key"
+	"github.com/giantswarm/aws-collector/service/internal/credential"
+)
+
+type ec2VPCMock struct {
+	ec2iface.EC2API
+
+	region string
+}
+
+func (e *ec2VPCMock) DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	o := &ec2.DescribeVpcsOutput{
+		Vpcs: []*ec2.Vpc{
+			{
+				CidrBlock: aws.String("10.1.0.0/16"),
+				State:     aws.String("available"),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(key.TagInstallation), Value: aws.String("test")},
+				},
+				VpcId: aws.String("vpc-" + e.region),
+			},
+		},
+	}
+
+	return o, nil
+}
+
+// Test_VPC_Collect_Regions ensures that VPCs are collected in every configured
+// region of an account and labelled with the region they are located in.
+func Test_VPC_Collect_Regions(t *testing.T) {
+	awsConfig := clientaws.Config{
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		Region:          "eu-central-1",
+	}
+
+	k8sClient := fake.NewFakeClientWithScheme(
+		newTestScheme(t),
+		newTestCredential(credential.DefaultName, "arn:aws:iam::111111111111:role/GiantSwarmAWSOperator"),
+	)
+
+	var h *helper
+	{
+		c := helperConfig{
+			K8sClient: k8sClient,
+			Logger:    microloggertest.New(),
+
+			AWSConfig:       awsConfig,
+			DiscoveryMaxAge: time.Minute,
+			Regions:         []string{"eu-west-1", "eu-central-1"},
+		}
+
+		var err error
+		h, err = newHelper(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h.clientPool = newTestClientPool(t, awsConfig, func(region string) ec2iface.EC2API {
+			return &ec2VPCMock{region: region}
+		})
+	}
+
+	var v *VPC
+	{
+		c := VPCConfig{
+			Helper: h,
+			Logger: microloggertest.New(),
+
+			InstallationName: "test",
+		}
+
+		var err error
+		v, err = NewVPC(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	err := v.Collect(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var vpcs []string
+	var up []string
+	for m := range ch {
+		var pb dto.Metric
+		err := m.Write(&pb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		labels := map[string]string{}
+		for _, l := range pb.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		switch m.Desc() {
+		case vpcsDesc:
+			vpcs = append(vpcs, fmt.Sprintf("%s/%s/%s", labels[labelAccountID], labels[labelRegion], labels[labelID]))
+		case collectorAccountUpDesc:
+			up = append(up, fmt.Sprintf("%s/%s=%v", labels[labelAccountID], labels[labelRegion], readGauge(t, m)))
+		}
+	}
+	sort.Strings(vpcs)
+	sort.Strings(up)
+
+	{
+		expected := []string{
+			"000000000000/eu-central-1/vpc-eu-central-1",
+			"000000000000/eu-west-1/vpc-eu-west-1",
+			"111111111111/eu-central-1/vpc-eu-central-1",
+			"111111111111/eu-west-1/vpc-eu-west-1",
+		}
+		if !cmp.Equal(vpcs, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, vpcs))
+		}
+	}
+
+	{
+		expected := []string{
+			"000000000000/eu-central-1=1",
+			"000000000000/eu-west-1=1",
+			"111111111111/eu-central-1=1",
+			"111111111111/eu-west-1=1",
+		}
+		if !cmp.Equal(up, expected) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, up))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package key
 
 import (
 	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/apiextensions/v3/pkg/label"
 )
 
 const (
@@ -77,6 +78,17 @@ const (
 	ComponentOS = "containerlinux"
 )
 
+// ClusterID returns the ID of the given cluster, which its AWS resources are
+// tagged with. Clusters without cluster label are identified by their name.
+func ClusterID(cluster infrastructurev1alpha3.AWSCluster) string {
+	id := cluster.GetLabels()[label.Cluster]
+	if id == "" {
+		return cluster.GetName()
+	}
+
+	return id
+}
+
 func CredentialName(cluster infrastructurev1alpha3.AWSCluster) string {
 	return cluster.Spec.Provider.CredentialSecret.Name
 }
@@ -84,3 +96,9 @@ func CredentialName(cluster infrastructurev1alpha3.AWSCluster) string {
 func CredentialNamespace(cluster infrastructurev1alpha3.AWSCluster) string {
 	return cluster.Spec.Provider.CredentialSecret.Namespace
 }
+
+// OrganizationID returns the organization the given cluster belongs to, which
+// its AWS resources are tagged with.
+func OrganizationID(cluster infrastructurev1alpha3.AWSCluster) string {
+	return cluster.GetLabels()[label.Organization]
+}
@@ -0,0 +1,24 @@
+package key
+
+import (
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/apiextensions/v3/pkg/label"
+)
+
+// MachineDeploymentClusterID returns the ID of the cluster the given node pool
+// belongs to.
+func MachineDeploymentClusterID(md infrastructurev1alpha3.AWSMachineDeployment) string {
+	return md.GetLabels()[label.Cluster]
+}
+
+// MachineDeploymentID returns the ID of the given node pool, which its ASG is
+// tagged with. Node pools without machine deployment label are identified by
+// their name.
+func MachineDeploymentID(md infrastructurev1alpha3.AWSMachineDeployment) string {
+	id := md.GetLabels()[label.MachineDeployment]
+	if id == "" {
+		return md.GetName()
+	}
+
+	return id
+}
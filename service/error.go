@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var cacheSyncError = &microerror.Error{
+	Kind: "cacheSyncError",
+}
+
+// IsCacheSync asserts cacheSyncError.
+func IsCacheSync(err error) bool {
+	return microerror.Cause(err) == cacheSyncError
+}
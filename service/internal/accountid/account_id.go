@@ -81,14 +81,27 @@ func (a *AccountID) lookup() (string, error) {
 		arn = *o.Arn
 	}
 
-	var id string
-	{
-		id = strings.Split(arn, ":")[accountIDIndex]
+	id, err := FromARN(arn)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
 
-		err := validateAccountID(id)
-		if err != nil {
-			return "", microerror.Mask(err)
-		}
+	return id, nil
+}
+
+// FromARN returns the account ID contained in the given ARN, e.g.
+// 123456789012 for arn:aws:iam::123456789012:role/example.
+func FromARN(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) <= accountIDIndex {
+		return "", microerror.Maskf(invalidAccountIDError, "ARN %#q must contain an account ID", arn)
+	}
+
+	id := parts[accountIDIndex]
+
+	err := validateAccountID(id)
+	if err != nil {
+		return "", microerror.Mask(err)
 	}
 
 	return id, nil
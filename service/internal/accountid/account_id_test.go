@@ -0,0 +1,61 @@
+package accountid
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_FromARN(t *testing.T) {
+	testCases := []struct {
+		name string
+		arn  string
+
+		expectedID    string
+		expectedError bool
+	}{
+		{
+			name: "case 0: role ARN",
+			arn:  "arn:aws:iam::123456789012:role/GiantSwarmAWSOperator",
+
+			expectedID:    "123456789012",
+			expectedError: false,
+		},
+		{
+			name: "case 1: assumed role ARN",
+			arn:  "arn:aws:sts::123456789012:assumed-role/GiantSwarmAWSOperator/session",
+
+			expectedID:    "123456789012",
+			expectedError: false,
+		},
+		{
+			name: "case 2: missing account ID",
+			arn:  "arn:aws:iam:",
+
+			expectedID:    "",
+			expectedError: true,
+		},
+		{
+			name: "case 3: invalid account ID",
+			arn:  "arn:aws:iam::12345:role/GiantSwarmAWSOperator",
+
+			expectedID:    "",
+			expectedError: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			id, err := FromARN(tc.arn)
+
+			if id != tc.expectedID {
+				t.Fatalf("expected %q, got %q", tc.expectedID, id)
+			}
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error response to be %v, got %v", tc.expectedError, err)
+			}
+			if err != nil && !IsInvalidAccountID(err) {
+				t.Fatalf("expected invalidAccountIDError, got %#v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package clientpool
+
+import (
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+	"github.com/giantswarm/aws-collector/service/internal/accountid"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// AWSConfig is the configuration of the control plane account. Clients of
+	// tenant cluster accounts are created by assuming their role ARN using the
+	// control plane credentials.
+	AWSConfig clientaws.Config
+	// NewClients creates the AWS clients of the pool. It defaults to
+	// clientaws.NewClients and is only meant to be replaced in tests.
+	NewClients func(config clientaws.Config) (clientaws.Clients, error)
+}
+
+// Pool is a long living, concurrency safe pool of AWS clients keyed by role
+// ARN and region. The clients of the control plane account are kept under the
+// empty ARN. Clients are reused across collections so that assumed role
+// credentials are only requested again when they are about to expire, and the
+// account ID of every ARN is only looked up once.
+type Pool struct {
+	logger micrologger.Logger
+
+	awsConfig  clientaws.Config
+	entries    map[string]*entry
+	mutex      sync.Mutex
+	newClients func(config clientaws.Config) (clientaws.Clients, error)
+}
+
+type entry struct {
+	accountID *accountid.AccountID
+	// clients holds the clients of the role ARN keyed by region.
+	clients map[string]clientaws.Clients
+}
+
+func New(config Config) (*Pool, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	var emptyAWSConfig clientaws.Config
+	if config.AWSConfig == emptyAWSConfig {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AWSConfig must not be empty", config)
+	}
+
+	newClients := config.NewClients
+	if newClients == nil {
+		newClients = clientaws.NewClients
+	}
+
+	p := &Pool{
+		logger: config.Logger,
+
+		awsConfig:  config.AWSConfig,
+		entries:    make(map[string]*entry),
+		mutex:      sync.Mutex{},
+		newClients: newClients,
+	}
+
+	return p, nil
+}
+
+// Get returns the AWS clients for the given role ARN and region. The clients
+// are created on first use and reused afterwards. The empty ARN returns the
+// clients of the control plane account.
+func (p *Pool) Get(arn string, region string) (clientaws.Clients, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	e, err := p.entry(arn)
+	if err != nil {
+		return clientaws.Clients{}, microerror.Mask(err)
+	}
+
+	awsClients, ok := e.clients[region]
+	if ok {
+		return awsClients, nil
+	}
+
+	awsClients, err = p.create(arn, region)
+	if err != nil {
+		return clientaws.Clients{}, microerror.Mask(err)
+	}
+	e.clients[region] = awsClients
+
+	return awsClients, nil
+}
+
+// AccountID returns the ID of the AWS account the clients of the given role
+// ARN operate in. Successful lookups are memoized for as long as the ARN is
+// part of the pool.
+func (p *Pool) AccountID(arn string) (string, error) {
+	p.mutex.Lock()
+	e, err := p.entry(arn)
+	p.mutex.Unlock()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	accountID, err := e.accountID.Lookup()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return accountID, nil
+}
+
+// Retain evicts the clients of all role ARNs which are not given, e.g. because
+// the clusters using them got deleted. The clients of the control plane
+// account are never evicted.
+func (p *Pool) Retain(arns []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	retain := map[string]bool{
+		"": true,
+	}
+	for _, arn := range arns {
+		retain[arn] = true
+	}
+
+	for arn := range p.entries {
+		if retain[arn] {
+			continue
+		}
+
+		p.logger.Log("level", "debug", "message", "evicting AWS clients of role ARN no longer in use", "arn", arn)
+		delete(p.entries, arn)
+	}
+}
+
+// entry returns the pool entry of the given role ARN, creating it with the
+// clients of the control plane region if necessary. The pool's mutex must be
+// held by the caller.
+func (p *Pool) entry(arn string) (*entry, error) {
+	e, ok := p.entries[arn]
+	if ok {
+		return e, nil
+	}
+
+	awsClients, err := p.create(arn, p.awsConfig.Region)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var accountIDService *accountid.AccountID
+	{
+		c := accountid.Config{
+			Logger: p.logger,
+			STS:    awsClients.STS,
+		}
+
+		accountIDService, err = accountid.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	e = &entry{
+		accountID: accountIDService,
+		clients: map[string]clientaws.Clients{
+			p.awsConfig.Region: awsClients,
+		},
+	}
+	p.entries[arn] = e
+
+	return e, nil
+}
+
+func (p *Pool) create(arn string, region string) (clientaws.Clients, error) {
+	awsConfig := p.awsConfig
+	awsConfig.Region = region
+	awsConfig.RoleARN = arn
+
+	awsClients, err := p.newClients(awsConfig)
+	if err != nil {
+		return clientaws.Clients{}, microerror.Mask(err)
+	}
+
+	return awsClients, nil
+}
@@ -0,0 +1,165 @@
+package clientpool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	clientaws "github.com/giantswarm/aws-collector/client/aws"
+)
+
+type stsMock struct {
+	stsiface.STSAPI
+
+	accountID string
+	calls     int
+	mutex     sync.Mutex
+}
+
+func (s *stsMock) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls++
+
+	o := &sts.GetCallerIdentityOutput{
+		Arn: aws.String(fmt.Sprintf("arn:aws:sts::%s:assumed-role/GiantSwarmAWSOperator/session", s.accountID)),
+	}
+
+	return o, nil
+}
+
+func newTestPool(t *testing.T) (*Pool, map[string]*stsMock) {
+	mocks := map[string]*stsMock{}
+
+	c := Config{
+		Logger: microloggertest.New(),
+
+		AWSConfig: clientaws.Config{
+			AccessKeyID:     "id",
+			AccessKeySecret: "secret",
+			Region:          "eu-central-1",
+		},
+		NewClients: func(config clientaws.Config) (clientaws.Clients, error) {
+			accountID := "000000000000"
+			if config.RoleARN != "" {
+				accountID = strings.Split(config.RoleARN, ":")[4]
+			}
+
+			m := &stsMock{accountID: accountID}
+			mocks[config.RoleARN+"/"+config.Region] = m
+
+			return clientaws.Clients{Region: config.Region, STS: m}, nil
+		},
+	}
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p, mocks
+}
+
+func Test_Pool_AccountID_Memoized(t *testing.T) {
+	p, mocks := newTestPool(t)
+
+	arn := "arn:aws:iam::123456789012:role/GiantSwarmAWSOperator"
+
+	for i := 0; i < 3; i++ {
+		accountID, err := p.AccountID(arn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accountID != "123456789012" {
+			t.Fatalf("expected %q, got %q", "123456789012", accountID)
+		}
+	}
+
+	if mocks[arn+"/eu-central-1"].calls != 1 {
+		t.Fatalf("expected 1 GetCallerIdentity call, got %d", mocks[arn+"/eu-central-1"].calls)
+	}
+}
+
+func Test_Pool_Get_Reuses(t *testing.T) {
+	p, mocks := newTestPool(t)
+
+	arn := "arn:aws:iam::123456789012:role/GiantSwarmAWSOperator"
+
+	first, err := p.Get(arn, "eu-central-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Get(arn, "eu-central-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.STS != second.STS {
+		t.Fatalf("expected clients to be reused")
+	}
+	if len(mocks) != 1 {
+		t.Fatalf("expected clients to be created once, got %d", len(mocks))
+	}
+}
+
+func Test_Pool_Get_Regions(t *testing.T) {
+	p, mocks := newTestPool(t)
+
+	arn := "arn:aws:iam::123456789012:role/GiantSwarmAWSOperator"
+
+	for _, region := range []string{"eu-central-1", "eu-west-1", "eu-west-1"} {
+		awsClients, err := p.Get(arn, region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if awsClients.Region != region {
+			t.Fatalf("expected clients in region %q, got %q", region, awsClients.Region)
+		}
+	}
+
+	if len(mocks) != 2 {
+		t.Fatalf("expected clients to be created once per region, got %d", len(mocks))
+	}
+}
+
+func Test_Pool_Retain(t *testing.T) {
+	p, mocks := newTestPool(t)
+
+	kept := "arn:aws:iam::123456789012:role/GiantSwarmAWSOperator"
+	evicted := "arn:aws:iam::210987654321:role/GiantSwarmAWSOperator"
+
+	for _, arn := range []string{"", kept, evicted} {
+		_, err := p.AccountID(arn)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.Retain([]string{kept})
+
+	if len(p.entries) != 2 {
+		t.Fatalf("expected 2 pooled entries, got %d", len(p.entries))
+	}
+	if _, ok := p.entries[""]; !ok {
+		t.Fatalf("expected control plane clients to be retained")
+	}
+	if _, ok := p.entries[evicted]; ok {
+		t.Fatalf("expected clients of %q to be evicted", evicted)
+	}
+
+	// Evicted clients are created from scratch on next use.
+	_, err := p.AccountID(evicted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mocks[evicted+"/eu-central-1"].calls != 1 {
+		t.Fatalf("expected new clients for %q", evicted)
+	}
+}
@@ -6,8 +6,7 @@ import (
 	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
 	"github.com/giantswarm/microerror"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/aws-collector/service/controller/key"
 )
@@ -23,7 +22,7 @@ const (
 	DefaultNamespace = "giantswarm"
 )
 
-func GetARN(ctx context.Context, k8sClient kubernetes.Interface, cr infrastructurev1alpha3.AWSCluster) (string, error) {
+func GetARN(ctx context.Context, k8sClient client.Reader, cr infrastructurev1alpha3.AWSCluster) (string, error) {
 	var err error
 
 	var credential *corev1.Secret
@@ -38,7 +37,8 @@ func GetARN(ctx context.Context, k8sClient kubernetes.Interface, cr infrastructu
 			return "", microerror.Mask(credentialNamespaceEmpty)
 		}
 
-		credential, err = k8sClient.CoreV1().Secrets(credentialNamespace).Get(ctx, credentialName, metav1.GetOptions{})
+		credential = &corev1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: credentialNamespace, Name: credentialName}, credential)
 		if err != nil {
 			return "", microerror.Mask(err)
 		}
@@ -54,8 +54,9 @@ func GetARN(ctx context.Context, k8sClient kubernetes.Interface, cr infrastructu
 
 // GetDefaultARN is used only by the bridgezone resource. It should be removed
 // when the resource is removed.
-func GetDefaultARN(ctx context.Context, k8sClient kubernetes.Interface) (string, error) {
-	credential, err := k8sClient.CoreV1().Secrets(DefaultNamespace).Get(ctx, DefaultName, metav1.GetOptions{})
+func GetDefaultARN(ctx context.Context, k8sClient client.Reader) (string, error) {
+	credential := &corev1.Secret{}
+	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: DefaultNamespace, Name: DefaultName}, credential)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
@@ -0,0 +1,72 @@
+package credential
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ReaderConfig struct {
+	// Reader serves all objects except secrets, e.g. a cluster wide informer
+	// cache of the cluster CRs.
+	Reader client.Reader
+	// SecretReader serves the secrets of DefaultNamespace, e.g. an informer
+	// cache restricted to that namespace.
+	SecretReader client.Reader
+	// LiveReader serves secrets of all other namespaces. These are only read
+	// for clusters referencing credentials outside of DefaultNamespace, so
+	// they are not worth caching all secrets of the cluster for.
+	LiveReader client.Reader
+}
+
+// Reader routes reads of credential secrets to a reader restricted to
+// DefaultNamespace, so that neither all secrets of the cluster have to be
+// kept in memory nor listing them has to be permitted.
+type Reader struct {
+	reader       client.Reader
+	secretReader client.Reader
+	liveReader   client.Reader
+}
+
+func NewReader(config ReaderConfig) (*Reader, error) {
+	if config.Reader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Reader must not be empty", config)
+	}
+	if config.SecretReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretReader must not be empty", config)
+	}
+	if config.LiveReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.LiveReader must not be empty", config)
+	}
+
+	r := &Reader{
+		reader:       config.Reader,
+		secretReader: config.SecretReader,
+		liveReader:   config.LiveReader,
+	}
+
+	return r, nil
+}
+
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return r.reader.Get(ctx, key, obj)
+	}
+
+	if key.Namespace == DefaultNamespace {
+		return r.secretReader.Get(ctx, key, obj)
+	}
+
+	return r.liveReader.Get(ctx, key, obj)
+}
+
+func (r *Reader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if _, ok := list.(*corev1.SecretList); ok {
+		return r.secretReader.List(ctx, list, opts...)
+	}
+
+	return r.reader.List(ctx, list, opts...)
+}
@@ -0,0 +1,60 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_Reader_Get(t *testing.T) {
+	s := runtime.NewScheme()
+	err := corev1.AddToScheme(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = infrastructurev1alpha3.AddToScheme(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := &infrastructurev1alpha3.AWSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "al9qy", Namespace: "default"},
+	}
+	cached := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credential-cached", Namespace: DefaultNamespace},
+	}
+	live := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credential-live", Namespace: "org-acme"},
+	}
+
+	// Every object is only known to the reader it has to be read from.
+	r, err := NewReader(ReaderConfig{
+		Reader:       fake.NewFakeClientWithScheme(s, cluster),
+		SecretReader: fake.NewFakeClientWithScheme(s, cached),
+		LiveReader:   fake.NewFakeClientWithScheme(s, live),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	err = r.Get(ctx, client.ObjectKey{Name: "al9qy", Namespace: "default"}, &infrastructurev1alpha3.AWSCluster{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Get(ctx, client.ObjectKey{Name: "credential-cached", Namespace: DefaultNamespace}, &corev1.Secret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Get(ctx, client.ObjectKey{Name: "credential-live", Namespace: "org-acme"}, &corev1.Secret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
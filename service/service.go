@@ -4,7 +4,10 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v3/pkg/apis/infrastructure/v1alpha3"
 	releasev1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/release/v1alpha1"
@@ -14,13 +17,23 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	apiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 
 	"github.com/giantswarm/aws-collector/client/aws"
 	"github.com/giantswarm/aws-collector/flag"
 	"github.com/giantswarm/aws-collector/pkg/project"
 	"github.com/giantswarm/aws-collector/service/collector"
+	"github.com/giantswarm/aws-collector/service/internal/credential"
+)
+
+const (
+	// cacheSyncTimeout is the time syncing the informer caches is waited for
+	// before it is retried.
+	cacheSyncTimeout = time.Minute
 )
 
 // Config represents the configuration used to create a new service.
@@ -35,6 +48,8 @@ type Service struct {
 	Version *version.Service
 
 	bootOnce          sync.Once
+	caches            []cache.Cache
+	logger            micrologger.Logger
 	operatorCollector *collector.Set
 }
 
@@ -93,6 +108,71 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	// k8sCache serves the CRs the collectors discover clusters from. It is
+	// kept up to date by informers, so that scrapes do not hit the Kubernetes
+	// API and new or deleted clusters are picked up without restarts.
+	var k8sCache cache.Cache
+	{
+		c := cache.Options{
+			Scheme: k8sClient.Scheme(),
+		}
+
+		k8sCache, err = cache.New(k8sClient.RESTConfig(), c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// Informers are registered up front so that they are all started and
+		// synced on boot.
+		objects := []runtime.Object{
+			&infrastructurev1alpha3.AWSCluster{},
+			&infrastructurev1alpha3.AWSControlPlane{},
+			&infrastructurev1alpha3.AWSMachineDeployment{},
+		}
+		for _, o := range objects {
+			_, err = k8sCache.GetInformer(context.Background(), o)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	// secretCache serves the credential secrets the collectors discover AWS
+	// accounts from. It is restricted to the credential namespace, so that
+	// secrets of other namespaces are neither kept in memory nor have to be
+	// listable by the collector.
+	var secretCache cache.Cache
+	{
+		c := cache.Options{
+			Namespace: credential.DefaultNamespace,
+			Scheme:    k8sClient.Scheme(),
+		}
+
+		secretCache, err = cache.New(k8sClient.RESTConfig(), c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		_, err = secretCache.GetInformer(context.Background(), &corev1.Secret{})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var k8sReader *credential.Reader
+	{
+		c := credential.ReaderConfig{
+			Reader:       k8sCache,
+			SecretReader: secretCache,
+			LiveReader:   k8sClient.CtrlClient(),
+		}
+
+		k8sReader, err = credential.NewReader(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var awsConfig aws.Config
 	{
 		awsConfig = aws.Config{
@@ -103,15 +183,56 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	var pollingInterval time.Duration
+	var pollingIntervals map[string]time.Duration
+	if config.Viper.GetBool(config.Flag.Service.Collector.Polling.Enabled) {
+		pollingInterval, err = time.ParseDuration(config.Viper.GetString(config.Flag.Service.Collector.Polling.Interval))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pollingIntervals, err = parsePollingIntervals(config.Viper.GetString(config.Flag.Service.Collector.Polling.Intervals))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var regionDiscoveryTTL time.Duration
+	if config.Viper.GetBool(config.Flag.Service.AWS.RegionDiscovery.Enabled) {
+		regionDiscoveryTTL, err = time.ParseDuration(config.Viper.GetString(config.Flag.Service.AWS.RegionDiscovery.TTL))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	serviceQuotas, err := parseServiceQuotas(config.Viper.GetString(config.Flag.Service.Collector.ServiceQuota.Quotas))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	snapshotRetention, err := time.ParseDuration(config.Viper.GetString(config.Flag.Service.Collector.Snapshot.Retention))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	var operatorCollector *collector.Set
 	{
 		c := collector.SetConfig{
-			Clients: k8sClient,
-			Logger:  config.Logger,
+			K8sClient: k8sReader,
+			Logger:    config.Logger,
 
-			AWSConfig:             awsConfig,
-			InstallationName:      config.Viper.GetString(config.Flag.Service.Installation.Name),
-			TrustedAdvisorEnabled: config.Viper.GetBool(config.Flag.Service.AWS.TrustedAdvisor.Enabled),
+			AWSConfig:                 awsConfig,
+			InstallationName:          config.Viper.GetString(config.Flag.Service.Installation.Name),
+			PollingEnabled:            config.Viper.GetBool(config.Flag.Service.Collector.Polling.Enabled),
+			PollingInterval:           pollingInterval,
+			PollingIntervals:          pollingIntervals,
+			RegionDiscoveryEnabled:    config.Viper.GetBool(config.Flag.Service.AWS.RegionDiscovery.Enabled),
+			RegionDiscoveryTTL:        regionDiscoveryTTL,
+			Regions:                   parseList(config.Viper.GetString(config.Flag.Service.AWS.Regions)),
+			ServiceQuotas:             serviceQuotas,
+			SnapshotRetention:         snapshotRetention,
+			TagComplianceRequiredTags: parseList(config.Viper.GetString(config.Flag.Service.Collector.TagCompliance.RequiredTags)),
+			TrustedAdvisorEnabled:     config.Viper.GetBool(config.Flag.Service.AWS.TrustedAdvisor.Enabled),
 		}
 
 		operatorCollector, err = collector.NewSet(c)
@@ -140,6 +261,8 @@ func New(config Config) (*Service, error) {
 		Version: versionService,
 
 		bootOnce:          sync.Once{},
+		caches:            []cache.Cache{k8sCache, secretCache},
+		logger:            config.Logger,
 		operatorCollector: operatorCollector,
 	}
 
@@ -148,6 +271,129 @@ func New(config Config) (*Service, error) {
 
 func (s *Service) Boot(ctx context.Context) {
 	s.bootOnce.Do(func() {
+		for _, c := range s.caches {
+			go func(c cache.Cache) {
+				err := c.Start(ctx.Done())
+				if err != nil {
+					s.logger.LogCtx(ctx, "level", "error", "message", "failed running informers", "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+				}
+			}(c)
+		}
+
+		// The collectors must not be registered before the caches are
+		// synced, as they would otherwise report all clusters as gone.
+		err := waitForCacheSync(ctx, s.caches, cacheSyncTimeout)
+		if err != nil {
+			s.logger.LogCtx(ctx, "level", "error", "message", "failed syncing informer caches", "stack", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return
+		}
+
 		go s.operatorCollector.Boot(ctx) // nolint: errcheck
 	})
 }
+
+// waitForCacheSync waits for the given caches to be synced. Syncing is
+// retried every timeout until it succeeds, so that a temporarily unavailable
+// Kubernetes API does not leave the service without collectors. An error is
+// only returned once ctx is done.
+func waitForCacheSync(ctx context.Context, caches []cache.Cache, timeout time.Duration) error {
+	for {
+		synced := true
+		for _, c := range caches {
+			syncCtx, cancel := context.WithTimeout(ctx, timeout)
+			ok := c.WaitForCacheSync(syncCtx.Done())
+			cancel()
+
+			if !ok {
+				synced = false
+				break
+			}
+		}
+
+		if synced {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return microerror.Maskf(cacheSyncError, "%s", ctx.Err())
+		default:
+		}
+	}
+}
+
+// parsePollingIntervals parses a comma separated list of per collector polling
+// intervals like servicequota=12h,elb=5m.
+func parsePollingIntervals(s string) (map[string]time.Duration, error) {
+	intervals := map[string]time.Duration{}
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, microerror.Maskf(invalidConfigError, "polling interval %#q must have the format <collector>=<duration>", item)
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		intervals[strings.TrimSpace(parts[0])] = d
+	}
+
+	return intervals, nil
+}
+
+// parseList parses a comma separated list like eu-west-1,us-east-1. Empty
+// items are dropped.
+func parseList(s string) []string {
+	var items []string
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// parseServiceQuotas parses a comma separated list of service quotas like
+// vpc/L-FE5A380F=nat-gateway,ec2/L-0263D0A3=elastic-ips.
+func parseServiceQuotas(s string) ([]collector.ServiceQuotaSpec, error) {
+	var quotas []collector.ServiceQuotaSpec
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, microerror.Maskf(invalidConfigError, "service quota %#q must have the format <service code>/<quota code>=<name>", item)
+		}
+		codes := strings.SplitN(parts[0], "/", 2)
+		if len(codes) != 2 {
+			return nil, microerror.Maskf(invalidConfigError, "service quota %#q must have the format <service code>/<quota code>=<name>", item)
+		}
+
+		q := collector.ServiceQuotaSpec{
+			ServiceCode: strings.TrimSpace(codes[0]),
+			QuotaCode:   strings.TrimSpace(codes[1]),
+			Name:        strings.TrimSpace(parts[1]),
+		}
+
+		quotas = append(quotas, q)
+	}
+
+	return quotas, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// syncingCache is a cache which is synced after the given number of attempts
+// to wait for it.
+type syncingCache struct {
+	cache.Cache
+
+	attempts int
+	waited   int
+}
+
+func (c *syncingCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	c.waited++
+	if c.waited > c.attempts {
+		return true
+	}
+
+	<-stop
+	return false
+}
+
+func Test_waitForCacheSync(t *testing.T) {
+	testCases := []struct {
+		name          string
+		attempts      []int
+		timeout       time.Duration
+		expectedError bool
+	}{
+		{
+			name:     "case 0: synced right away",
+			attempts: []int{0, 0},
+		},
+		{
+			name:     "case 1: syncing is retried",
+			attempts: []int{2, 1},
+		},
+		{
+			name:          "case 2: context done before synced",
+			attempts:      []int{0, 1000},
+			timeout:       100 * time.Millisecond,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			var caches []cache.Cache
+			for _, attempts := range tc.attempts {
+				caches = append(caches, &syncingCache{attempts: attempts})
+			}
+
+			err := waitForCacheSync(ctx, caches, time.Millisecond)
+
+			if tc.expectedError {
+				if !IsCacheSync(err) {
+					t.Fatalf("expected cache sync error, got %#v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}